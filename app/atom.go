@@ -0,0 +1,176 @@
+package app
+
+import (
+	"encoding/xml"
+	"strings"
+)
+
+type AtomFeed struct {
+	Base     string      `xml:"http://www.w3.org/XML/1998/namespace base,attr"`
+	Title    AtomText    `xml:"title"`
+	Subtitle AtomText    `xml:"subtitle"`
+	Links    []AtomLink  `xml:"link"`
+	Entries  []AtomEntry `xml:"entry"`
+}
+
+type AtomEntry struct {
+	Base       string         `xml:"http://www.w3.org/XML/1998/namespace base,attr"`
+	ID         string         `xml:"id"`
+	Title      AtomText       `xml:"title"`
+	Links      []AtomLink     `xml:"link"`
+	Updated    string         `xml:"updated"`
+	Published  string         `xml:"published"`
+	Summary    AtomText       `xml:"summary"`
+	Content    AtomText       `xml:"content"`
+	Authors    []AtomPerson   `xml:"author"`
+	Categories []AtomCategory `xml:"category"`
+}
+
+// AtomText is an atom text construct. Text and html content is character
+// data, while xhtml content is markup wrapped in a <div>.
+type AtomText struct {
+	Type  string `xml:"type,attr"`
+	Text  string `xml:",chardata"`
+	Inner string `xml:",innerxml"`
+}
+
+// HTML returns the construct's content for storing as a description: the
+// markup of xhtml content, and the character data of text and html content.
+func (t AtomText) HTML() string {
+	if t.Type != "xhtml" {
+		return t.Text
+	}
+	return xhtmlDivContent(t.Inner)
+}
+
+// String returns the construct's content as plain text.
+func (t AtomText) String() string {
+	if t.Type != "xhtml" {
+		return t.Text
+	}
+
+	var text strings.Builder
+	decoder := xml.NewDecoder(strings.NewReader(t.Inner))
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return strings.TrimSpace(text.String())
+		}
+		if data, ok := token.(xml.CharData); ok {
+			text.Write(data)
+		}
+	}
+}
+
+// xhtmlDivContent returns the markup inside the <div> that wraps xhtml
+// content, dropping the div's namespace prefix from the elements it
+// contains, as in <xhtml:div><xhtml:p>.
+func xhtmlDivContent(inner string) string {
+	inner = strings.TrimSpace(inner)
+	openEnd := strings.IndexByte(inner, '>')
+	closeStart := strings.LastIndex(inner, "</")
+	if !strings.HasPrefix(inner, "<") || openEnd < 0 {
+		return inner
+	}
+	if strings.HasSuffix(inner[:openEnd], "/") {
+		return ""
+	}
+	if closeStart <= openEnd {
+		return inner
+	}
+
+	content := inner[openEnd+1 : closeStart]
+	if prefix, _, found := strings.Cut(inner[1:openEnd], ":"); found && !strings.ContainsAny(prefix, " \t\r\n") {
+		content = strings.NewReplacer("<"+prefix+":", "<", "</"+prefix+":", "</").Replace(content)
+	}
+	return strings.TrimSpace(content)
+}
+
+type AtomPerson struct {
+	Name string `xml:"name"`
+}
+
+type AtomCategory struct {
+	Term  string `xml:"term,attr"`
+	Label string `xml:"label,attr"`
+}
+
+type AtomLink struct {
+	Href   string `xml:"href,attr"`
+	Rel    string `xml:"rel,attr"`
+	Type   string `xml:"type,attr"`
+	Length string `xml:"length,attr"`
+}
+
+// alternateLink returns the href of the rel="alternate" link, which is also
+// the default relation when rel is omitted.
+func alternateLink(links []AtomLink) string {
+	for _, link := range links {
+		if link.Rel == "" || link.Rel == "alternate" {
+			return strings.TrimSpace(link.Href)
+		}
+	}
+	return ""
+}
+
+func (a *AtomFeed) toRSS() *RSSFeed {
+	var rssFeed RSSFeed
+	rssFeed.Channel.Title = a.Title.String()
+	rssFeed.Channel.Link = resolveReference(a.Base, alternateLink(a.Links))
+	rssFeed.Channel.Description = a.Subtitle.String()
+
+	for _, entry := range a.Entries {
+		base := a.Base
+		if entry.Base != "" {
+			base = resolveReference(a.Base, entry.Base)
+		}
+
+		pubDate := entry.Published
+		if pubDate == "" {
+			pubDate = entry.Updated
+		}
+
+		content := entry.Content.HTML()
+		description := entry.Summary.HTML()
+		if description == "" {
+			description = content
+		}
+
+		var authors []string
+		for _, author := range entry.Authors {
+			if name := strings.TrimSpace(author.Name); name != "" {
+				authors = append(authors, name)
+			}
+		}
+
+		var categories []string
+		for _, category := range entry.Categories {
+			if category.Label != "" {
+				categories = append(categories, category.Label)
+			} else {
+				categories = append(categories, category.Term)
+			}
+		}
+
+		var enclosures []RSSEnclosure
+		for _, link := range entry.Links {
+			if link.Rel == "enclosure" {
+				enclosures = append(enclosures, RSSEnclosure{URL: resolveReference(base, link.Href), Type: link.Type, Length: link.Length})
+			}
+		}
+
+		rssFeed.Channel.Item = append(rssFeed.Channel.Item, RSSItem{
+			GUID:        entry.ID,
+			Title:       entry.Title.String(),
+			Link:        resolveReference(base, alternateLink(entry.Links)),
+			Description: description,
+			PubDate:     strings.TrimSpace(pubDate),
+			Author:      strings.Join(authors, ", "),
+			Content:     content,
+			Categories:  categories,
+			Enclosures:  enclosures,
+		})
+	}
+
+	return &rssFeed
+}
@@ -0,0 +1,182 @@
+package app
+
+import (
+	"encoding/xml"
+	"reflect"
+	"testing"
+)
+
+func TestParseFeedAtom(t *testing.T) {
+	data := []byte(`<?xml version="1.0" encoding="utf-8"?>
+<feed xmlns="http://www.w3.org/2005/Atom">
+	<title type="html">Tom &amp;amp; Jerry</title>
+	<subtitle>Cartoons</subtitle>
+	<link rel="self" href="https://example.com/atom.xml"/>
+	<link href="https://example.com/"/>
+	<entry>
+		<id>urn:uuid:1</id>
+		<title>First</title>
+		<link rel="alternate" href="https://example.com/first"/>
+		<link rel="enclosure" href="https://example.com/first.mp3" type="audio/mpeg" length="1234"/>
+		<updated>2006-01-03T00:00:00Z</updated>
+		<published>2006-01-02T15:04:05Z</published>
+		<author><name>Jane</name></author>
+		<author><name> John </name></author>
+		<category term="go" label="Go"/>
+		<category term="news"/>
+		<summary>Short</summary>
+		<content type="html">&lt;p&gt;Long&lt;/p&gt;</content>
+	</entry>
+	<entry>
+		<id>urn:uuid:2</id>
+		<title>Second</title>
+		<updated>2006-01-04T00:00:00Z</updated>
+		<content type="html"><![CDATA[<p>Only content</p>]]></content>
+	</entry>
+</feed>`)
+
+	feed, err := parseFeed(data, "application/atom+xml")
+	if err != nil {
+		t.Fatalf("parseFeed returned error: %v", err)
+	}
+
+	if feed.Channel.Title != "Tom &amp; Jerry" || feed.Channel.Description != "Cartoons" || feed.Channel.Link != "https://example.com/" {
+		t.Errorf("got channel %q, %q, %q", feed.Channel.Title, feed.Channel.Description, feed.Channel.Link)
+	}
+
+	want := []RSSItem{
+		{
+			GUID:        "urn:uuid:1",
+			Title:       "First",
+			Link:        "https://example.com/first",
+			Description: "Short",
+			PubDate:     "2006-01-02T15:04:05Z",
+			Author:      "Jane, John",
+			Content:     "<p>Long</p>",
+			Categories:  []string{"Go", "news"},
+			Enclosures:  []RSSEnclosure{{URL: "https://example.com/first.mp3", Type: "audio/mpeg", Length: "1234"}},
+		},
+		{
+			GUID:        "urn:uuid:2",
+			Title:       "Second",
+			Description: "<p>Only content</p>",
+			PubDate:     "2006-01-04T00:00:00Z",
+			Content:     "<p>Only content</p>",
+		},
+	}
+	if !reflect.DeepEqual(feed.Channel.Item, want) {
+		t.Errorf("items = %+v\nwant %+v", feed.Channel.Item, want)
+	}
+}
+
+func TestParseFeedAtomBase(t *testing.T) {
+	data := []byte(`<feed xmlns="http://www.w3.org/2005/Atom" xml:base="https://example.com/blog/">
+	<title>t</title>
+	<link href="./"/>
+	<entry><title>a</title><link href="/posts/a"/></entry>
+	<entry xml:base="2024/"><title>b</title><link href="b.html"/></entry>
+	<entry xml:base="https://other.example.com/"><title>c</title><link href="c"/></entry>
+	<entry><title>d</title><link href="https://example.org/d"/></entry>
+</feed>`)
+
+	feed, err := parseFeed(data, "")
+	if err != nil {
+		t.Fatalf("parseFeed returned error: %v", err)
+	}
+	if feed.Channel.Link != "https://example.com/blog/" {
+		t.Errorf("channel link = %q", feed.Channel.Link)
+	}
+	want := []string{
+		"https://example.com/posts/a",
+		"https://example.com/blog/2024/b.html",
+		"https://other.example.com/c",
+		"https://example.org/d",
+	}
+	for i, item := range feed.Channel.Item {
+		if item.Link != want[i] {
+			t.Errorf("item %d link = %q, want %q", i, item.Link, want[i])
+		}
+	}
+}
+
+func TestAtomText(t *testing.T) {
+	tests := []struct {
+		name     string
+		element  string
+		wantHTML string
+		wantText string
+	}{
+		{
+			name:     "text",
+			element:  `<content>plain &amp; simple</content>`,
+			wantHTML: "plain & simple",
+			wantText: "plain & simple",
+		},
+		{
+			name:     "escaped html",
+			element:  `<content type="html">&lt;p&gt;hi&lt;/p&gt;</content>`,
+			wantHTML: "<p>hi</p>",
+			wantText: "<p>hi</p>",
+		},
+		{
+			name:     "html in cdata",
+			element:  `<content type="html"><![CDATA[<p>hi</p>]]></content>`,
+			wantHTML: "<p>hi</p>",
+			wantText: "<p>hi</p>",
+		},
+		{
+			name:     "xhtml",
+			element:  `<content type="xhtml"><div xmlns="http://www.w3.org/1999/xhtml"><p>Hello <em>world</em></p></div></content>`,
+			wantHTML: "<p>Hello <em>world</em></p>",
+			wantText: "Hello world",
+		},
+		{
+			name:     "prefixed xhtml",
+			element:  `<content type="xhtml" xmlns:x="http://www.w3.org/1999/xhtml"><x:div> <x:p>Hi</x:p> </x:div></content>`,
+			wantHTML: "<p>Hi</p>",
+			wantText: "Hi",
+		},
+		{
+			name:     "empty xhtml",
+			element:  `<content type="xhtml"><div xmlns="http://www.w3.org/1999/xhtml"/></content>`,
+			wantHTML: "",
+			wantText: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var text AtomText
+			if err := xml.Unmarshal([]byte(tt.element), &text); err != nil {
+				t.Fatal(err)
+			}
+			if got := text.HTML(); got != tt.wantHTML {
+				t.Errorf("HTML() = %q, want %q", got, tt.wantHTML)
+			}
+			if got := text.String(); got != tt.wantText {
+				t.Errorf("String() = %q, want %q", got, tt.wantText)
+			}
+		})
+	}
+}
+
+func TestAlternateLink(t *testing.T) {
+	tests := []struct {
+		name  string
+		links []AtomLink
+		want  string
+	}{
+		{"none", nil, ""},
+		{"default rel", []AtomLink{{Rel: "self", Href: "s"}, {Href: " a "}}, "a"},
+		{"explicit rel", []AtomLink{{Rel: "enclosure", Href: "e"}, {Rel: "alternate", Href: "a"}}, "a"},
+		{"no alternate", []AtomLink{{Rel: "self", Href: "s"}}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := alternateLink(tt.links); got != tt.want {
+				t.Errorf("alternateLink = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
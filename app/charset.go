@@ -0,0 +1,64 @@
+package app
+
+import (
+	"bytes"
+	"encoding/xml"
+	"mime"
+	"regexp"
+	"unicode/utf8"
+
+	"golang.org/x/net/html/charset"
+	"golang.org/x/text/encoding/charmap"
+)
+
+// xmlEncodingDecl matches the encoding attribute of a leading XML declaration.
+var xmlEncodingDecl = regexp.MustCompile(`^(\s*<\?xml[^>]*?\sencoding\s*=\s*)(?:"([^"]*)"|'([^']*)')`)
+
+// newXMLDecoder returns a decoder that understands the encodings a document
+// may declare, such as ISO-8859-1, windows-1252 and Shift_JIS.
+func newXMLDecoder(data []byte) *xml.Decoder {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	decoder.CharsetReader = charset.NewReaderLabel
+	return decoder
+}
+
+// toUTF8 transcodes a response body to UTF-8, going by the charset in its
+// Content-Type, which takes precedence, or else its XML declaration. The
+// declaration is rewritten to match so the XML decoder does not decode the
+// body twice.
+func toUTF8(data []byte, contentType string) ([]byte, error) {
+	_, params, _ := mime.ParseMediaType(contentType)
+	enc, name := charset.Lookup(params["charset"])
+
+	// Servers often label everything UTF-8 by default. A body that is not
+	// valid UTF-8 is better served by what the document itself declares.
+	if enc != nil && name == "utf-8" && !utf8.Valid(data) {
+		enc = nil
+	}
+
+	if enc == nil {
+		enc, _ = charset.Lookup(declaredEncoding(data))
+	}
+	if enc == nil {
+		if declaredEncoding(data) != "" || utf8.Valid(data) {
+			return data, nil
+		}
+		// Undeclared and not UTF-8: windows-1252 is by far the most
+		// common culprit, and a superset of ISO-8859-1.
+		enc = charmap.Windows1252
+	}
+
+	decoded, err := enc.NewDecoder().Bytes(data)
+	if err != nil {
+		return nil, err
+	}
+	return xmlEncodingDecl.ReplaceAll(decoded, []byte(`${1}"UTF-8"`)), nil
+}
+
+func declaredEncoding(data []byte) string {
+	match := xmlEncodingDecl.FindSubmatch(data)
+	if match == nil {
+		return ""
+	}
+	return string(match[2]) + string(match[3])
+}
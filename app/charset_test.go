@@ -0,0 +1,97 @@
+package app
+
+import (
+	"testing"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/japanese"
+)
+
+func encode(t *testing.T, enc encoding.Encoding, s string) []byte {
+	t.Helper()
+	data, err := enc.NewEncoder().Bytes([]byte(s))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestToUTF8(t *testing.T) {
+	tests := []struct {
+		name        string
+		data        []byte
+		contentType string
+		want        string
+	}{
+		{
+			name: "utf-8 unchanged",
+			data: []byte(`<?xml version="1.0" encoding="UTF-8"?><title>café</title>`),
+			want: `<?xml version="1.0" encoding="UTF-8"?><title>café</title>`,
+		},
+		{
+			name: "declared ISO-8859-1",
+			data: encode(t, charmap.ISO8859_1, `<?xml version="1.0" encoding="ISO-8859-1"?><title>café</title>`),
+			want: `<?xml version="1.0" encoding="UTF-8"?><title>café</title>`,
+		},
+		{
+			name: "declared with single quotes",
+			data: encode(t, charmap.ISO8859_1, `<?xml version='1.0' encoding='iso-8859-1'?><title>café</title>`),
+			want: `<?xml version='1.0' encoding="UTF-8"?><title>café</title>`,
+		},
+		{
+			name: "declared Shift_JIS",
+			data: encode(t, japanese.ShiftJIS, `<?xml version="1.0" encoding="Shift_JIS"?><title>日本語</title>`),
+			want: `<?xml version="1.0" encoding="UTF-8"?><title>日本語</title>`,
+		},
+		{
+			name:        "http charset takes precedence",
+			data:        encode(t, charmap.Windows1252, `<?xml version="1.0" encoding="UTF-8"?><title>“quoted”</title>`),
+			contentType: "application/rss+xml; charset=windows-1252",
+			want:        `<?xml version="1.0" encoding="UTF-8"?><title>“quoted”</title>`,
+		},
+		{
+			name:        "mislabeled utf-8 falls back to declaration",
+			data:        encode(t, charmap.ISO8859_1, `<?xml version="1.0" encoding="ISO-8859-1"?><title>café</title>`),
+			contentType: "text/xml; charset=utf-8",
+			want:        `<?xml version="1.0" encoding="UTF-8"?><title>café</title>`,
+		},
+		{
+			name: "undeclared windows-1252",
+			data: encode(t, charmap.Windows1252, `<title>café – “quoted”</title>`),
+			want: `<title>café – “quoted”</title>`,
+		},
+		{
+			name: "undeclared utf-8",
+			data: []byte(`<title>café</title>`),
+			want: `<title>café</title>`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := toUTF8(tt.data, tt.contentType)
+			if err != nil {
+				t.Fatalf("toUTF8 returned error: %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("toUTF8 = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseFeedDeclaredEncoding(t *testing.T) {
+	// A document that was not transcoded first still decodes through the
+	// decoder's CharsetReader.
+	data := encode(t, charmap.ISO8859_1, `<?xml version="1.0" encoding="ISO-8859-1"?>
+<rss><channel><title>Café</title><item><title>Crème brûlée</title></item></channel></rss>`)
+
+	feed, err := parseFeed(data, "")
+	if err != nil {
+		t.Fatalf("parseFeed returned error: %v", err)
+	}
+	if feed.Channel.Title != "Café" || feed.Channel.Item[0].Title != "Crème brûlée" {
+		t.Errorf("got titles %q and %q", feed.Channel.Title, feed.Channel.Item[0].Title)
+	}
+}
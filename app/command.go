@@ -1,6 +1,9 @@
 package app
 
-import "fmt"
+import (
+	"context"
+	"fmt"
+)
 
 type Command struct {
 	Name string
@@ -8,17 +11,17 @@ type Command struct {
 }
 
 type Commands struct {
-	RegisteredCommands map[string]func(*State, Command) error
+	RegisteredCommands map[string]func(context.Context, *State, Command) error
 }
 
-func (c *Commands) Register(name string, f func(*State, Command) error) {
+func (c *Commands) Register(name string, f func(context.Context, *State, Command) error) {
 	c.RegisteredCommands[name] = f
 }
 
-func (c *Commands) Run(s *State, cmd Command) error {
+func (c *Commands) Run(ctx context.Context, s *State, cmd Command) error {
 	f, ok := c.RegisteredCommands[cmd.Name]
 	if !ok {
 		return fmt.Errorf("command is not registered %s", cmd.Name)
 	}
-	return f(s, cmd)
+	return f(ctx, s, cmd)
 }
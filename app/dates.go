@@ -0,0 +1,129 @@
+package app
+
+import (
+	"strings"
+	"time"
+	"unicode"
+)
+
+// pubDateLayouts are tried in order after parsePubDate has dropped any
+// weekday and rewritten named zones as numeric offsets.
+var pubDateLayouts = []string{
+	// RFC 822 / RFC 1123 without the weekday, with four and two digit years.
+	"2 Jan 2006 15:04:05 -0700",
+	"2 Jan 2006 15:04 -0700",
+	"2 Jan 06 15:04:05 -0700",
+	"2 Jan 06 15:04 -0700",
+	"2 Jan 2006 15:04:05 Z07:00",
+	"2 January 2006 15:04:05 -0700",
+	"2 January 2006 15:04 -0700",
+	"2 Jan 2006 15:04:05",
+	"2 Jan 2006",
+	"Jan 2 2006 15:04:05 -0700",
+	"Jan 2, 2006 15:04:05 -0700",
+	"January 2, 2006",
+
+	// RFC 3339 / ISO 8601. Fractional seconds are accepted by any layout
+	// with a seconds field.
+	time.RFC3339,
+	"2006-01-02T15:04:05-0700",
+	"2006-01-02T15:04Z07:00",
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05Z07:00",
+	"2006-01-02 15:04:05 -0700",
+	"2006-01-02 15:04:05 Z07:00",
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+}
+
+// namedZones maps the zone names that show up in feeds to fixed offsets.
+// time.Parse only knows the offset of the local zone's abbreviations.
+var namedZones = map[string]string{
+	"UT":   "+0000",
+	"UTC":  "+0000",
+	"GMT":  "+0000",
+	"Z":    "+0000",
+	"EST":  "-0500",
+	"EDT":  "-0400",
+	"CST":  "-0600",
+	"CDT":  "-0500",
+	"MST":  "-0700",
+	"MDT":  "-0600",
+	"PST":  "-0800",
+	"PDT":  "-0700",
+	"AKST": "-0900",
+	"AKDT": "-0800",
+	"HST":  "-1000",
+	"BST":  "+0100",
+	"WET":  "+0000",
+	"WEST": "+0100",
+	"CET":  "+0100",
+	"CEST": "+0200",
+	"MET":  "+0100",
+	"MEST": "+0200",
+	"EET":  "+0200",
+	"EEST": "+0300",
+	"MSK":  "+0300",
+	"JST":  "+0900",
+	"KST":  "+0900",
+	"AEST": "+1000",
+	"AEDT": "+1100",
+	"NZST": "+1200",
+	"NZDT": "+1300",
+}
+
+// parsePubDate parses the publication dates found in real feeds. It reports
+// false when none of the known formats match.
+func parsePubDate(value string) (time.Time, bool) {
+	fields := strings.Fields(value)
+	if len(fields) == 0 {
+		return time.Time{}, false
+	}
+
+	// Weekdays are redundant and often localized ("Mon,", "lun.,", "Di"),
+	// so drop a leading word rather than trying to recognize it.
+	if len(fields) > 1 && isWeekdayToken(fields[0], fields[1]) {
+		fields = fields[1:]
+	}
+
+	last := len(fields) - 1
+	if offset, ok := namedZones[strings.ToUpper(fields[last])]; ok && last > 0 {
+		fields[last] = offset
+	}
+
+	normalized := strings.Join(fields, " ")
+	for _, layout := range pubDateLayouts {
+		if t, err := time.Parse(layout, normalized); err == nil {
+			return t, true
+		}
+	}
+
+	return time.Time{}, false
+}
+
+// isWeekdayToken reports whether token looks like a day name: a word
+// followed by a comma, or a bare word directly before the day of month.
+func isWeekdayToken(token, next string) bool {
+	word := strings.TrimRight(token, ".,")
+	if word == "" || isMonthName(word) {
+		return false
+	}
+	for _, r := range word {
+		if !unicode.IsLetter(r) {
+			return false
+		}
+	}
+
+	if strings.HasSuffix(token, ",") {
+		return true
+	}
+	return unicode.IsDigit([]rune(next)[0])
+}
+
+func isMonthName(word string) bool {
+	if _, err := time.Parse("Jan", word); err == nil {
+		return true
+	}
+	_, err := time.Parse("January", word)
+	return err == nil
+}
@@ -0,0 +1,61 @@
+package app
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParsePubDate(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		want  string
+	}{
+		{"RFC 1123", "Mon, 02 Jan 2006 15:04:05 -0700", "2006-01-02T15:04:05-07:00"},
+		{"RFC 1123 with GMT", "Mon, 02 Jan 2006 15:04:05 GMT", "2006-01-02T15:04:05Z"},
+		{"named zone", "Tue, 10 Jun 2003 04:00:00 EDT", "2003-06-10T04:00:00-04:00"},
+		{"lowercase zone", "10 Jun 2003 04:00:00 cest", "2003-06-10T04:00:00+02:00"},
+		{"no weekday", "02 Jan 2006 15:04:05 +0000", "2006-01-02T15:04:05Z"},
+		{"single digit day", "Mon, 2 Jan 2006 15:04:05 +0000", "2006-01-02T15:04:05Z"},
+		{"two digit year", "Mon, 02 Jan 06 15:04:05 +0000", "2006-01-02T15:04:05Z"},
+		{"no seconds", "Mon, 02 Jan 2006 15:04 +0000", "2006-01-02T15:04:00Z"},
+		{"full month name", "2 January 2006 15:04:05 +0100", "2006-01-02T15:04:05+01:00"},
+		{"localized weekday", "lun., 02 Jan 2006 15:04:05 +0100", "2006-01-02T15:04:05+01:00"},
+		{"weekday without comma", "Di 02 Jan 2006 15:04:05 +0100", "2006-01-02T15:04:05+01:00"},
+		{"extra whitespace", "  Mon,  02 Jan 2006   15:04:05 +0000 ", "2006-01-02T15:04:05Z"},
+		{"no zone", "02 Jan 2006 15:04:05", "2006-01-02T15:04:05Z"},
+		{"date only", "02 Jan 2006", "2006-01-02T00:00:00Z"},
+		{"month first", "Jan 2, 2006 15:04:05 +0000", "2006-01-02T15:04:05Z"},
+		{"long month first", "January 2, 2006", "2006-01-02T00:00:00Z"},
+		{"RFC 3339", "2006-01-02T15:04:05Z", "2006-01-02T15:04:05Z"},
+		{"RFC 3339 fractional", "2006-01-02T15:04:05.123+02:00", "2006-01-02T15:04:05.123+02:00"},
+		{"ISO 8601 compact offset", "2006-01-02T15:04:05+0200", "2006-01-02T15:04:05+02:00"},
+		{"ISO 8601 no seconds", "2006-01-02T15:04Z", "2006-01-02T15:04:00Z"},
+		{"ISO 8601 space", "2006-01-02 15:04:05", "2006-01-02T15:04:05Z"},
+		{"ISO 8601 date", "2006-01-02", "2006-01-02T00:00:00Z"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := parsePubDate(tt.value)
+			if !ok {
+				t.Fatalf("parsePubDate(%q) failed", tt.value)
+			}
+			want, err := time.Parse(time.RFC3339Nano, tt.want)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !got.Equal(want) {
+				t.Errorf("parsePubDate(%q) = %v, want %v", tt.value, got, want)
+			}
+		})
+	}
+}
+
+func TestParsePubDateInvalid(t *testing.T) {
+	for _, value := range []string{"", "   ", "yesterday", "32 Jan 2006", "Mon, 02 Foo 2006 15:04:05 +0000"} {
+		if got, ok := parsePubDate(value); ok {
+			t.Errorf("parsePubDate(%q) = %v, want failure", value, got)
+		}
+	}
+}
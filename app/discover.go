@@ -0,0 +1,178 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// feedLinkTypes are the <link type> values that announce a feed.
+var feedLinkTypes = map[string]bool{
+	"application/rss+xml":   true,
+	"application/atom+xml":  true,
+	"application/feed+json": true,
+}
+
+// FeedCandidate is a feed announced by a web page.
+type FeedCandidate struct {
+	URL   string
+	Title string
+	Type  string
+}
+
+// DiscoverFeeds looks up pageURL. When it serves a web page rather than a
+// feed, DiscoverFeeds returns the feeds the page announces with
+// <link rel="alternate">, which may be none. Otherwise pageURL is taken to be
+// a feed and returned as the only candidate.
+func (f *Fetcher) DiscoverFeeds(ctx context.Context, pageURL string) ([]FeedCandidate, error) {
+	if pageURL == "" {
+		return nil, errors.New("no url given")
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", pageURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error in creating request: %w", err)
+	}
+	req.Header.Set("User-Agent", "gator")
+
+	res, err := f.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error in making the request: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return nil, &StatusError{StatusCode: res.StatusCode, Status: res.Status}
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(res.Header.Get("Content-Type"))
+	if !isHTMLType(mediaType) {
+		return []FeedCandidate{{URL: pageURL, Type: mediaType}}, nil
+	}
+
+	data, err := io.ReadAll(io.LimitReader(res.Body, f.maxBodyBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("error reading response body: %w", err)
+	}
+	if int64(len(data)) > f.maxBodyBytes {
+		return nil, &BodyTooLargeError{Limit: f.maxBodyBytes}
+	}
+	if looksLikeFeed(data) {
+		return []FeedCandidate{{URL: pageURL, Type: mediaType}}, nil
+	}
+
+	// Relative links resolve against where the page ended up after any
+	// redirects.
+	return feedLinks(data, res.Request.URL), nil
+}
+
+// feedLinks returns the feeds announced in an html document's
+// <link rel="alternate"> tags, resolved against base or the document's own
+// <base href>.
+func feedLinks(data []byte, base *url.URL) []FeedCandidate {
+	var candidates []FeedCandidate
+	seen := map[string]bool{}
+
+	tokenizer := html.NewTokenizer(bytes.NewReader(data))
+	for {
+		switch tokenizer.Next() {
+		case html.ErrorToken:
+			return candidates
+		case html.StartTagToken, html.SelfClosingTagToken:
+		default:
+			continue
+		}
+
+		name, hasAttr := tokenizer.TagName()
+		if !hasAttr || (string(name) != "link" && string(name) != "base") {
+			continue
+		}
+
+		attrs := map[string]string{}
+		for more := true; more; {
+			var key, value []byte
+			key, value, more = tokenizer.TagAttr()
+			attrs[string(key)] = strings.TrimSpace(string(value))
+		}
+
+		href, err := base.Parse(attrs["href"])
+		if attrs["href"] == "" || err != nil {
+			continue
+		}
+		if string(name) == "base" {
+			base = href
+			continue
+		}
+
+		linkType := strings.ToLower(attrs["type"])
+		if !hasToken(attrs["rel"], "alternate") || !feedLinkTypes[linkType] || seen[href.String()] {
+			continue
+		}
+		seen[href.String()] = true
+		candidates = append(candidates, FeedCandidate{
+			URL:   href.String(),
+			Title: attrs["title"],
+			Type:  linkType,
+		})
+	}
+}
+
+// isWebPage reports whether err is FetchFeed rejecting a web page, which may
+// announce the feed that was meant.
+func isWebPage(err error) bool {
+	var typeErr *ContentTypeError
+	if !errors.As(err, &typeErr) {
+		return false
+	}
+	mediaType, _, _ := mime.ParseMediaType(typeErr.ContentType)
+	return isHTMLType(mediaType)
+}
+
+// hasToken reports whether the space-separated list contains token,
+// ignoring case, as in rel="alternate feed".
+func hasToken(list, token string) bool {
+	for _, field := range strings.Fields(list) {
+		if strings.EqualFold(field, token) {
+			return true
+		}
+	}
+	return false
+}
+
+// resolveFeedURL turns an address someone typed into a feed url, looking on
+// the page for its feed when the address is a website's. When the page
+// announces several feeds it lists them and asks for one.
+func resolveFeedURL(ctx context.Context, s *State, rawURL string) (string, error) {
+	candidates, err := s.Fetcher.DiscoverFeeds(ctx, rawURL)
+	if err != nil {
+		return "", fmt.Errorf("could not look up %s: %w", rawURL, err)
+	}
+
+	switch len(candidates) {
+	case 0:
+		return "", fmt.Errorf("%s is a web page that does not link to a feed", rawURL)
+	case 1:
+		if candidates[0].URL != rawURL {
+			fmt.Printf("Found feed %s\n", candidates[0].URL)
+		}
+		return candidates[0].URL, nil
+	}
+
+	fmt.Printf("%s links to several feeds:\n", rawURL)
+	for _, candidate := range candidates {
+		if candidate.Title != "" {
+			fmt.Printf("* %s (%s)\n", candidate.URL, candidate.Title)
+		} else {
+			fmt.Printf("* %s\n", candidate.URL)
+		}
+	}
+	return "", errors.New("run the command again with one of the feed urls above")
+}
@@ -0,0 +1,87 @@
+package app
+
+import (
+	"fmt"
+	"net/url"
+	"reflect"
+	"testing"
+)
+
+func TestFeedLinks(t *testing.T) {
+	tests := []struct {
+		name string
+		page string
+		want []FeedCandidate
+	}{
+		{
+			name: "relative and absolute links",
+			page: `<html><head>
+				<link rel="alternate" type="application/rss+xml" title="RSS" href="/feed.xml">
+				<link rel="alternate" type="application/atom+xml" href="https://other.example.com/atom">
+				<link rel="alternate" type="application/feed+json" href="feed.json">
+			</head></html>`,
+			want: []FeedCandidate{
+				{URL: "https://example.com/feed.xml", Title: "RSS", Type: "application/rss+xml"},
+				{URL: "https://other.example.com/atom", Type: "application/atom+xml"},
+				{URL: "https://example.com/blog/feed.json", Type: "application/feed+json"},
+			},
+		},
+		{
+			name: "base href",
+			page: `<head><base href="https://cdn.example.com/site/"><link rel="alternate" type="application/rss+xml" href="rss"></head>`,
+			want: []FeedCandidate{{URL: "https://cdn.example.com/site/rss", Type: "application/rss+xml"}},
+		},
+		{
+			name: "rel and type matched loosely",
+			page: `<link rel="Alternate feed" type="Application/RSS+XML" href=" /feed ">`,
+			want: []FeedCandidate{{URL: "https://example.com/feed", Type: "application/rss+xml"}},
+		},
+		{
+			name: "duplicates dropped",
+			page: `<link rel="alternate" type="application/rss+xml" href="/feed"><link rel="alternate" type="application/rss+xml" href="https://example.com/feed">`,
+			want: []FeedCandidate{{URL: "https://example.com/feed", Type: "application/rss+xml"}},
+		},
+		{
+			name: "other links ignored",
+			page: `<link rel="stylesheet" href="/style.css">
+				<link rel="alternate" type="text/html" hreflang="fr" href="/fr/">
+				<link rel="alternate" type="application/rss+xml">
+				<a rel="alternate" type="application/rss+xml" href="/a-feed">feed</a>`,
+			want: nil,
+		},
+	}
+
+	base, err := url.Parse("https://example.com/blog/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := feedLinks([]byte(tt.page), base); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("feedLinks = %+v\nwant %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsWebPage(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"html page", fmt.Errorf("could not verify feed: %w", &ContentTypeError{ContentType: "text/html; charset=utf-8"}), true},
+		{"xhtml page", &ContentTypeError{ContentType: "application/xhtml+xml"}, true},
+		{"image", &ContentTypeError{ContentType: "image/png"}, false},
+		{"not found", &StatusError{StatusCode: 404, Status: "404 Not Found"}, false},
+		{"no error", nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isWebPage(tt.err); got != tt.want {
+				t.Errorf("isWebPage(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
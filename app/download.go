@@ -0,0 +1,209 @@
+package app
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/fotis-sofoulis/blog-aggregator/internal/database"
+	"github.com/google/uuid"
+)
+
+var HandlerDownload = func(ctx context.Context, s *State, cmd Command, user database.User) error {
+	fs := flag.NewFlagSet(cmd.Name, flag.ContinueOnError)
+	feedURL := fs.String("feed", "", "download only this feed's enclosures")
+	dir := fs.String("dir", "downloads", "directory to save the files in")
+	cmdArgs, err := parseFlags(fs, cmd.Args)
+	if err != nil {
+		return err
+	}
+	if len(cmdArgs) != 0 {
+		return fmt.Errorf("usage: %s [--feed <url>] [--dir <path>]", cmd.Name)
+	}
+
+	args := database.GetPendingEnclosuresParams{UserID: user.ID}
+	if *feedURL != "" {
+		feed, err := s.Db.GetFeedByUrl(ctx, *feedURL)
+		if err != nil {
+			return fmt.Errorf("could not get feed by url: %w", err)
+		}
+		// Filter by id, as the url given may be an old address of the feed.
+		args.FeedID = uuid.NullUUID{UUID: feed.ID, Valid: true}
+	}
+
+	enclosures, err := s.Db.GetPendingEnclosures(ctx, args)
+	if err != nil {
+		return fmt.Errorf("couldn't get enclosures to download: %w", err)
+	}
+	if len(enclosures) == 0 {
+		fmt.Println("Nothing to download")
+		return nil
+	}
+
+	downloaded, failed := 0, 0
+	for _, enclosure := range enclosures {
+		if ctx.Err() != nil {
+			fmt.Println("Interrupted. Run download again to resume.")
+			break
+		}
+
+		localPath := enclosurePath(*dir, enclosure)
+		fmt.Printf("Downloading %s from %s...\n", enclosure.PostTitle, enclosure.FeedName)
+		if err := downloadEnclosure(ctx, s.Fetcher.downloadClient, enclosure.Url, localPath); err != nil {
+			fmt.Printf("Couldn't download %s: %v\n", enclosure.Url, err)
+			failed++
+			continue
+		}
+
+		params := database.MarkEnclosureDownloadedParams{
+			ID:        enclosure.ID,
+			LocalPath: sql.NullString{String: localPath, Valid: true},
+		}
+		if err := s.Db.MarkEnclosureDownloaded(context.WithoutCancel(ctx), params); err != nil {
+			fmt.Printf("Couldn't record download of %s: %v\n", enclosure.Url, err)
+			failed++
+			continue
+		}
+		downloaded++
+	}
+
+	fmt.Printf("Downloaded %d file(s), %d failed\n", downloaded, failed)
+	if failed > 0 {
+		return fmt.Errorf("%d download(s) failed", failed)
+	}
+	return nil
+}
+
+// enclosurePath places an enclosure under a directory named after its feed.
+// The file name starts with the enclosure's id, so two episodes that both
+// end in "episode.mp3" do not overwrite each other.
+func enclosurePath(dir string, enclosure database.GetPendingEnclosuresRow) string {
+	name := enclosure.ID.String()[:8]
+	if u, err := url.Parse(enclosure.Url); err == nil {
+		if base := path.Base(u.Path); base != "." && base != "/" {
+			name += "-" + safeFileName(base)
+		}
+	}
+	return filepath.Join(dir, safeFileName(enclosure.FeedName), name)
+}
+
+func safeFileName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		switch r {
+		case '/', '\\', ':', '*', '?', '"', '<', '>', '|':
+			return '_'
+		}
+		if r < ' ' {
+			return -1
+		}
+		return r
+	}, name)
+	name = strings.Trim(name, ". ")
+	if name == "" {
+		return "_"
+	}
+	return name
+}
+
+// downloadEnclosure saves fileURL to localPath. The body goes to a .part file
+// first, and a .part file left by an earlier attempt is resumed with a Range
+// request when the server supports it.
+func downloadEnclosure(ctx context.Context, client *http.Client, fileURL, localPath string) error {
+	if _, err := os.Stat(localPath); err == nil {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(localPath), 0o755); err != nil {
+		return fmt.Errorf("could not create download directory: %w", err)
+	}
+
+	partPath := localPath + ".part"
+	var offset int64
+	if info, err := os.Stat(partPath); err == nil {
+		offset = info.Size()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", fileURL, nil)
+	if err != nil {
+		return fmt.Errorf("error in creating request: %w", err)
+	}
+	req.Header.Set("User-Agent", "gator")
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+	}
+
+	res, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("error in making the request: %w", err)
+	}
+	defer res.Body.Close()
+
+	flags := os.O_CREATE | os.O_WRONLY
+	switch {
+	case res.StatusCode == http.StatusPartialContent && offset > 0:
+		var start int64
+		if _, err := fmt.Sscanf(res.Header.Get("Content-Range"), "bytes %d-", &start); err != nil || start != offset {
+			return fmt.Errorf("server resumed at the wrong offset: %q", res.Header.Get("Content-Range"))
+		}
+		flags |= os.O_APPEND
+	case res.StatusCode == http.StatusOK:
+		// The server ignored the Range header, so start over.
+		flags |= os.O_TRUNC
+	case res.StatusCode == http.StatusRequestedRangeNotSatisfiable && offset > 0:
+		// The .part file already holds the whole file.
+		return os.Rename(partPath, localPath)
+	default:
+		return fmt.Errorf("unexpected status: %s", res.Status)
+	}
+
+	file, err := os.OpenFile(partPath, flags, 0o644)
+	if err != nil {
+		return fmt.Errorf("could not open %s: %w", partPath, err)
+	}
+	_, copyErr := io.Copy(file, res.Body)
+	if err := errors.Join(copyErr, file.Close()); err != nil {
+		return fmt.Errorf("could not write %s: %w", partPath, err)
+	}
+
+	return os.Rename(partPath, localPath)
+}
+
+// enclosureDetails describes an enclosure's type, size and download for
+// browse.
+func enclosureDetails(enclosure database.PostEnclosure) string {
+	var details []string
+	if enclosure.MimeType.Valid {
+		details = append(details, enclosure.MimeType.String)
+	}
+	if enclosure.Length.Valid {
+		details = append(details, formatBytes(enclosure.Length.Int64))
+	}
+	if enclosure.LocalPath.Valid {
+		details = append(details, "saved to "+enclosure.LocalPath.String)
+	}
+	if len(details) == 0 {
+		return ""
+	}
+	return " (" + strings.Join(details, ", ") + ")"
+}
+
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,52 @@
+package app
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/fotis-sofoulis/blog-aggregator/internal/database"
+	"github.com/google/uuid"
+)
+
+func TestEnclosurePath(t *testing.T) {
+	id := uuid.MustParse("0123abcd-0000-0000-0000-000000000000")
+	tests := []struct {
+		name     string
+		feedName string
+		url      string
+		want     string
+	}{
+		{"file name", "Podcast", "https://example.com/ep/1.mp3?x=1", filepath.Join("dl", "Podcast", "0123abcd-1.mp3")},
+		{"unsafe feed name", "A/B: C?", "https://example.com/e.mp3", filepath.Join("dl", "A_B_ C_", "0123abcd-e.mp3")},
+		{"no file name", "..", "https://example.com/", filepath.Join("dl", "_", "0123abcd")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			enclosure := database.GetPendingEnclosuresRow{ID: id, Url: tt.url, FeedName: tt.feedName}
+			if got := enclosurePath("dl", enclosure); got != tt.want {
+				t.Errorf("enclosurePath = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatBytes(t *testing.T) {
+	tests := []struct {
+		n    int64
+		want string
+	}{
+		{0, "0 B"},
+		{1023, "1023 B"},
+		{1024, "1.0 KiB"},
+		{1536, "1.5 KiB"},
+		{5 << 20, "5.0 MiB"},
+		{3 << 30, "3.0 GiB"},
+	}
+
+	for _, tt := range tests {
+		if got := formatBytes(tt.n); got != tt.want {
+			t.Errorf("formatBytes(%d) = %q, want %q", tt.n, got, tt.want)
+		}
+	}
+}
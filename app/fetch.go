@@ -0,0 +1,157 @@
+package app
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/fotis-sofoulis/blog-aggregator/internal/config"
+)
+
+const (
+	defaultConnectTimeout = 10 * time.Second
+	defaultReadTimeout    = 30 * time.Second
+	defaultMaxBodyBytes   = 10 << 20
+)
+
+// Fetcher downloads feeds within the limits set in the config, so one bad
+// feed url cannot hang or exhaust the aggregator.
+type Fetcher struct {
+	client       *http.Client
+	maxBodyBytes int64
+
+	// downloadClient fetches enclosures. It shares the connect and response
+	// header timeouts but has no overall timeout, as a large file can take
+	// longer to download than any feed.
+	downloadClient *http.Client
+}
+
+func NewFetcher(cfg *config.Config) (*Fetcher, error) {
+	connectTimeout, err := configDuration(cfg.FetchConnectTimeout, defaultConnectTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("invalid fetch_connect_timeout: %w", err)
+	}
+	readTimeout, err := configDuration(cfg.FetchReadTimeout, defaultReadTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("invalid fetch_read_timeout: %w", err)
+	}
+	maxBodyBytes := cfg.FetchMaxBodyBytes
+	if maxBodyBytes <= 0 {
+		maxBodyBytes = defaultMaxBodyBytes
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = (&net.Dialer{Timeout: connectTimeout}).DialContext
+	transport.TLSHandshakeTimeout = connectTimeout
+	transport.ResponseHeaderTimeout = readTimeout
+
+	return &Fetcher{
+		// The client timeout also covers reading the body, which is what
+		// stops a server that trickles bytes forever.
+		client:         &http.Client{Transport: transport, Timeout: connectTimeout + readTimeout},
+		maxBodyBytes:   maxBodyBytes,
+		downloadClient: &http.Client{Transport: transport},
+	}, nil
+}
+
+// permanentRedirect returns the address a response was permanently
+// redirected to, following 301 and 308 responses up to the first temporary
+// redirect. It returns "" when there was no permanent redirect.
+func permanentRedirect(res *http.Response) string {
+	var hops []*http.Request
+	for req := res.Request; req.Response != nil; req = req.Response.Request {
+		hops = append(hops, req)
+	}
+
+	permanent := ""
+	for i := len(hops) - 1; i >= 0; i-- {
+		switch hops[i].Response.StatusCode {
+		case http.StatusMovedPermanently, http.StatusPermanentRedirect:
+			permanent = hops[i].URL.String()
+		default:
+			return permanent
+		}
+	}
+	return permanent
+}
+
+func configDuration(value string, fallback time.Duration) (time.Duration, error) {
+	if value == "" {
+		return fallback, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, err
+	}
+	if d <= 0 {
+		return 0, fmt.Errorf("must be positive, got %s", value)
+	}
+	return d, nil
+}
+
+// StatusError is returned for responses other than 2xx and 304.
+type StatusError struct {
+	StatusCode int
+	Status     string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("unexpected status: %s", e.Status)
+}
+
+// ContentTypeError is returned when the response is clearly not a feed, such
+// as an image, or an html page that is not an rss document in disguise.
+type ContentTypeError struct {
+	ContentType string
+}
+
+func (e *ContentTypeError) Error() string {
+	return fmt.Sprintf("not a feed: content type %q", e.ContentType)
+}
+
+// BodyTooLargeError is returned when the response is bigger than the
+// configured limit. The body is not read past the limit.
+type BodyTooLargeError struct {
+	Limit int64
+}
+
+func (e *BodyTooLargeError) Error() string {
+	return fmt.Sprintf("response body larger than %d bytes", e.Limit)
+}
+
+func isHTMLType(mediaType string) bool {
+	return mediaType == "text/html" || mediaType == "application/xhtml+xml"
+}
+
+// isNonFeedType reports whether no feed could be served with this media
+// type. Types that misconfigured servers commonly send feeds with, such as
+// text/plain and application/octet-stream, are let through to the parser.
+func isNonFeedType(mediaType string) bool {
+	if mediaType == "" || strings.HasSuffix(mediaType, "xml") || strings.HasSuffix(mediaType, "json") {
+		return false
+	}
+
+	topLevel, _, _ := strings.Cut(mediaType, "/")
+	switch topLevel {
+	case "image", "audio", "video", "font", "model", "multipart":
+		return true
+	case "application":
+		return mediaType != "application/octet-stream"
+	}
+	return false
+}
+
+// looksLikeFeed reports whether an xml body has a feed's root element.
+func looksLikeFeed(data []byte) bool {
+	root, err := rootElement(newXMLDecoder(data))
+	if err != nil {
+		return false
+	}
+	switch root {
+	case "rss", "feed", "RDF":
+		return true
+	}
+	return false
+}
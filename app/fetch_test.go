@@ -0,0 +1,226 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/fotis-sofoulis/blog-aggregator/internal/config"
+)
+
+const testRSS = `<?xml version="1.0"?><rss><channel><title>Test</title><item><title>a</title></item></channel></rss>`
+
+func newTestFetcher(t *testing.T, cfg config.Config) *Fetcher {
+	t.Helper()
+	fetcher, err := NewFetcher(&cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return fetcher
+}
+
+func TestNewFetcherInvalidConfig(t *testing.T) {
+	for _, cfg := range []config.Config{
+		{FetchConnectTimeout: "soon"},
+		{FetchReadTimeout: "-1s"},
+		{FetchReadTimeout: "0s"},
+	} {
+		if _, err := NewFetcher(&cfg); err == nil {
+			t.Errorf("NewFetcher(%+v) succeeded, want error", cfg)
+		}
+	}
+}
+
+func TestFetchFeed(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/feed", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("Content-Type", "application/rss+xml")
+		w.Header().Set("ETag", `"v1"`)
+		w.Write([]byte(testRSS))
+	})
+	mux.HandleFunc("/blog/relative", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/atom+xml")
+		w.Write([]byte(`<feed xmlns="http://www.w3.org/2005/Atom"><title>t</title><link href="/"/>
+			<entry><title>a</title><link href="posts/a"/></entry></feed>`))
+	})
+	mux.HandleFunc("/gone", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusGone)
+	})
+	mux.HandleFunc("/image", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/png")
+		w.Write([]byte("\x89PNG"))
+	})
+	mux.HandleFunc("/page", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte("<html><body>Not a feed</body></html>"))
+	})
+	mux.HandleFunc("/disguised", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write([]byte(testRSS))
+	})
+	mux.HandleFunc("/large", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/rss+xml")
+		w.Write([]byte(testRSS + strings.Repeat(" ", 1024)))
+	})
+	mux.HandleFunc("/malformed", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`<rss><channel><title>Test</title><item><title>a&nbsp;b</title></item></channel></rss>`))
+	})
+	mux.Handle("/moved", http.RedirectHandler("/feed", http.StatusMovedPermanently))
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	fetcher := newTestFetcher(t, config.Config{FetchMaxBodyBytes: 512})
+	ctx := context.Background()
+
+	t.Run("ok", func(t *testing.T) {
+		result, err := fetcher.FetchFeed(ctx, server.URL+"/feed", CacheValidators{})
+		if err != nil {
+			t.Fatalf("FetchFeed returned error: %v", err)
+		}
+		if result.Feed.Channel.Title != "Test" || len(result.Feed.Channel.Item) != 1 {
+			t.Errorf("got feed %+v", result.Feed.Channel)
+		}
+		if result.Validators.ETag != `"v1"` || result.StatusCode != http.StatusOK || result.Warning != "" {
+			t.Errorf("got validators %+v, status %d, warning %q", result.Validators, result.StatusCode, result.Warning)
+		}
+	})
+
+	t.Run("not modified", func(t *testing.T) {
+		result, err := fetcher.FetchFeed(ctx, server.URL+"/feed", CacheValidators{ETag: `"v1"`})
+		if err != nil {
+			t.Fatalf("FetchFeed returned error: %v", err)
+		}
+		if !result.NotModified || result.Feed != nil || result.Validators.ETag != `"v1"` {
+			t.Errorf("got %+v, want a not modified result", result)
+		}
+	})
+
+	t.Run("permanent redirect", func(t *testing.T) {
+		result, err := fetcher.FetchFeed(ctx, server.URL+"/moved", CacheValidators{})
+		if err != nil {
+			t.Fatalf("FetchFeed returned error: %v", err)
+		}
+		if result.PermanentURL != server.URL+"/feed" {
+			t.Errorf("PermanentURL = %q, want %q", result.PermanentURL, server.URL+"/feed")
+		}
+	})
+
+	t.Run("relative links", func(t *testing.T) {
+		result, err := fetcher.FetchFeed(ctx, server.URL+"/blog/relative", CacheValidators{})
+		if err != nil {
+			t.Fatalf("FetchFeed returned error: %v", err)
+		}
+		if result.Feed.Channel.Link != server.URL+"/" || result.Feed.Channel.Item[0].Link != server.URL+"/blog/posts/a" {
+			t.Errorf("got channel link %q and item link %q", result.Feed.Channel.Link, result.Feed.Channel.Item[0].Link)
+		}
+	})
+
+	t.Run("html disguised feed", func(t *testing.T) {
+		if _, err := fetcher.FetchFeed(ctx, server.URL+"/disguised", CacheValidators{}); err != nil {
+			t.Errorf("FetchFeed returned error: %v", err)
+		}
+	})
+
+	t.Run("malformed", func(t *testing.T) {
+		result, err := fetcher.FetchFeed(ctx, server.URL+"/malformed", CacheValidators{})
+		if err != nil {
+			t.Fatalf("FetchFeed returned error: %v", err)
+		}
+		if result.Warning == "" || result.Feed.Channel.Item[0].Title != "a b" {
+			t.Errorf("got warning %q and items %+v", result.Warning, result.Feed.Channel.Item)
+		}
+	})
+
+	var statusErr *StatusError
+	var typeErr *ContentTypeError
+	var sizeErr *BodyTooLargeError
+	tests := []struct {
+		path   string
+		target any
+	}{
+		{"/gone", &statusErr},
+		{"/missing", &statusErr},
+		{"/image", &typeErr},
+		{"/page", &typeErr},
+		{"/large", &sizeErr},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			_, err := fetcher.FetchFeed(ctx, server.URL+tt.path, CacheValidators{})
+			if !errors.As(err, tt.target) {
+				t.Errorf("FetchFeed error = %v, want %T", err, tt.target)
+			}
+		})
+	}
+}
+
+func TestPermanentRedirect(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.Handle("/a", http.RedirectHandler("/b", http.StatusMovedPermanently))
+	mux.Handle("/b", http.RedirectHandler("/c", http.StatusPermanentRedirect))
+	mux.Handle("/c", http.RedirectHandler("/d", http.StatusFound))
+	mux.Handle("/t", http.RedirectHandler("/a", http.StatusTemporaryRedirect))
+	mux.HandleFunc("/d", func(w http.ResponseWriter, r *http.Request) {})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"/d", ""},
+		{"/c", ""},
+		{"/b", "/c"},
+		{"/a", "/c"},
+		{"/t", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			res, err := http.Get(server.URL + tt.path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			res.Body.Close()
+
+			want := tt.want
+			if want != "" {
+				want = server.URL + want
+			}
+			if got := permanentRedirect(res); got != want {
+				t.Errorf("permanentRedirect = %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestIsNonFeedType(t *testing.T) {
+	tests := []struct {
+		mediaType string
+		want      bool
+	}{
+		{"", false},
+		{"application/rss+xml", false},
+		{"application/xml", false},
+		{"text/xml", false},
+		{"application/feed+json", false},
+		{"text/html", false},
+		{"text/plain", false},
+		{"application/octet-stream", false},
+		{"image/png", true},
+		{"audio/mpeg", true},
+		{"application/pdf", true},
+	}
+
+	for _, tt := range tests {
+		if got := isNonFeedType(tt.mediaType); got != tt.want {
+			t.Errorf("isNonFeedType(%q) = %v, want %v", tt.mediaType, got, tt.want)
+		}
+	}
+}
@@ -5,69 +5,356 @@ import (
 	"database/sql"
 	"fmt"
 	"errors"
+	"flag"
+	"net/http"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/fotis-sofoulis/blog-aggregator/internal/database"
 	"github.com/google/uuid"
 )
 
-func scrapeFeeds(s *State, ctx context.Context) {
-	feed, err := s.Db.GetNextFeedToFetch(ctx)
+// feedLease is how long a claimed feed stays reserved for the worker that
+// claimed it. It has to outlast a slow fetch.
+const feedLease = 5 * time.Minute
+
+type scrapeResult struct {
+	NotModified bool
+	StatusCode  int
+	Bytes       int64
+	Seen        int
+	Inserted    int
+	Updated     int
+	Skipped     int
+	Errored     int
+	Hints       updateHints
+	Warning     string
+
+	// PermanentURL is where the feed has permanently moved, if it has.
+	PermanentURL string
+}
+
+// scrapeFeed collects the feed's posts, records the attempt in its fetch
+// history and releases the feed's lease.
+func scrapeFeed(ctx context.Context, s *State, feed database.Feed) (scrapeResult, error) {
+	started := time.Now()
+	scraped, err := collectFeed(ctx, s, feed)
+	recordFeedFetch(ctx, s, feed, started, scraped, err)
+
 	if err != nil {
-		fmt.Printf("error fetching the feed from database")
+		backOffFeed(ctx, s, feed, err)
+	} else if err := scheduleFeed(ctx, s, feed, scraped.Hints); err != nil {
+		fmt.Printf("could not schedule feed %s: %v\n", feed.Url, err)
 	}
 
 	if err := s.Db.MarkFeedFetched(ctx, feed.ID); err != nil {
-		fmt.Printf("could not mark the feed as fetched")
+		fmt.Printf("could not mark the feed as fetched: %v\n", err)
+	}
+
+	if err == nil && scraped.PermanentURL != "" && scraped.PermanentURL != feed.Url {
+		if err := moveFeed(ctx, s, feed, scraped.PermanentURL); err != nil {
+			fmt.Printf("could not move feed %s to %s: %v\n", feed.Url, scraped.PermanentURL, err)
+		}
+	}
+
+	return scraped, err
+}
+
+const (
+	// maxConsecutiveFailures is how many failed fetches in a row disable a
+	// feed until someone runs feed-enable.
+	maxConsecutiveFailures = 10
+	minFeedBackoff         = 5 * time.Minute
+	maxFeedBackoff         = 24 * time.Hour
+)
+
+// feedBackoff doubles the wait before the next fetch with every consecutive
+// failure, up to maxFeedBackoff.
+func feedBackoff(failures int32) time.Duration {
+	backoff := minFeedBackoff
+	for i := int32(1); i < failures && backoff < maxFeedBackoff; i++ {
+		backoff *= 2
+	}
+	return min(backoff, maxFeedBackoff)
+}
+
+func backOffFeed(ctx context.Context, s *State, feed database.Feed, fetchErr error) {
+	failures := feed.ConsecutiveFailures + 1
+	nextFetch := time.Now().Add(feedBackoff(failures))
+
+	args := database.RecordFeedFailureParams{
+		ID:          feed.ID,
+		NextFetchAt: sql.NullTime{Time: nextFetch, Valid: true},
+	}
+	if err := s.Db.RecordFeedFailure(ctx, args); err != nil {
+		fmt.Printf("could not record failure of feed %s: %v\n", feed.Url, err)
+		return
 	}
 
-	rssFeed, err := FetchFeed(ctx, feed.Url)
+	var statusErr *StatusError
+	var reason string
+	switch {
+	case errors.As(fetchErr, &statusErr) && statusErr.StatusCode == http.StatusGone:
+		reason = "feed is gone (410)"
+	case failures >= maxConsecutiveFailures:
+		reason = fmt.Sprintf("%d consecutive failures, last: %v", failures, fetchErr)
+	default:
+		return
+	}
+
+	disableArgs := database.DisableFeedParams{
+		ID:             feed.ID,
+		DisabledReason: sql.NullString{String: reason, Valid: true},
+	}
+	if err := s.Db.DisableFeed(ctx, disableArgs); err != nil {
+		fmt.Printf("could not disable feed %s: %v\n", feed.Url, err)
+		return
+	}
+	fmt.Printf("Disabled feed %s: %s\n", feed.Url, reason)
+}
+
+// feedFetchRetention is how many fetch attempts are kept per feed. Older ones
+// are deleted as new ones are recorded.
+const feedFetchRetention = 100
+
+func recordFeedFetch(ctx context.Context, s *State, feed database.Feed, started time.Time, scraped scrapeResult, fetchErr error) {
+	args := database.CreateFeedFetchParams{
+		ID:            uuid.New(),
+		FeedID:        feed.ID,
+		FetchedAt:     started,
+		StatusCode:    sql.NullInt32{Int32: int32(scraped.StatusCode), Valid: scraped.StatusCode != 0},
+		DurationMs:    int32(time.Since(started).Milliseconds()),
+		Bytes:         scraped.Bytes,
+		ItemsSeen:     int32(scraped.Seen),
+		ItemsInserted: int32(scraped.Inserted),
+	}
+	if fetchErr != nil {
+		args.Error = sql.NullString{String: fetchErr.Error(), Valid: true}
+	}
+
+	if err := s.Db.CreateFeedFetch(ctx, args); err != nil {
+		fmt.Printf("could not record fetch of feed %s: %v\n", feed.Url, err)
+		return
+	}
+
+	pruneArgs := database.PruneFeedFetchesParams{FeedID: feed.ID, Limit: feedFetchRetention}
+	if err := s.Db.PruneFeedFetches(ctx, pruneArgs); err != nil {
+		fmt.Printf("could not prune fetch history of feed %s: %v\n", feed.Url, err)
+	}
+}
+
+func collectFeed(ctx context.Context, s *State, feed database.Feed) (scrapeResult, error) {
+	var scraped scrapeResult
+
+	validators := CacheValidators{ETag: feed.Etag.String, LastModified: feed.LastModified.String}
+	result, err := s.Fetcher.FetchFeed(ctx, feed.Url, validators)
+	if result != nil {
+		scraped.StatusCode = result.StatusCode
+		scraped.Bytes = result.Bytes
+		scraped.PermanentURL = result.PermanentURL
+	}
 	if err != nil {
-		fmt.Printf("could not fetch rss feed from url")
+		return scraped, fmt.Errorf("could not fetch feed %s: %w", feed.Url, err)
 	}
 
+	if result.NotModified {
+		scraped.NotModified = true
+		scraped.Hints = storedHints(feed)
+		return scraped, nil
+	}
+
+	rssFeed := result.Feed
+	scraped.Warning = result.Warning
+	scraped.Seen = len(rssFeed.Channel.Item)
+	scraped.Hints = rssFeed.updateHints()
 	for _, item := range rssFeed.Channel.Item {
 		if item.Title == "" {
+			scraped.Skipped++
 			continue
 		}
 
-		publishedAt := time.Now()
-		if t, err := time.Parse(time.RFC1123Z, item.PubDate); err == nil {
-			publishedAt = t
+		// Undated items are recorded at the time we first saw them, and
+		// flagged so browse can tell the date is only an estimate.
+		publishedAt, dated := parsePubDate(item.PubDate)
+		if !dated {
+			publishedAt = time.Now()
 		}
 
-		args := database.CreatePostParams{
-			ID:          uuid.New(),
-			CreatedAt:   time.Now(),
-			UpdatedAt:   time.Now(),
-			Title:       item.Title,
-			Url:         item.Link,
-			Description: sql.NullString{String: item.Description, Valid: item.Description != ""},
-			PublishedAt: publishedAt,
-			FeedID:      feed.ID,
+		guid := item.Identity()
+		postID := uuid.New()
+		args := database.UpsertPostParams{
+			ID:                   postID,
+			CreatedAt:            time.Now(),
+			UpdatedAt:            time.Now(),
+			Title:                item.Title,
+			Url:                  item.Link,
+			Description:          sql.NullString{String: item.Description, Valid: item.Description != ""},
+			PublishedAt:          publishedAt,
+			FeedID:               feed.ID,
+			PublishedAtEstimated: !dated,
+			Guid:                 guid,
+			ContentHash:          item.ContentHash(),
+			Author:               sql.NullString{String: item.Author, Valid: item.Author != ""},
+			Content:              sql.NullString{String: item.Content, Valid: item.Content != ""},
 		}
 
-		_, err := s.Db.CreatePost(ctx, args)
-		if err != nil {
-			if strings.Contains(err.Error(), "duplicate key value violates unique constraint") {
-				continue
+		// A post is stored together with its categories and enclosures, or
+		// not at all, so a failed item is retried whole on the next fetch.
+		var post database.Post
+		var replaced int64
+		err := s.withTx(ctx, func(q *database.Queries) error {
+			var err error
+			post, err = q.UpsertPost(ctx, args)
+			if err != nil {
+				return err
+			}
+			if post.ID == postID && item.Link != "" && guid != item.Link {
+				replaceParams := database.ReplaceLegacyPostParams{FeedID: feed.ID, Url: item.Link, ID: post.ID}
+				if replaced, err = q.ReplaceLegacyPost(ctx, replaceParams); err != nil {
+					return fmt.Errorf("could not replace legacy post: %w", err)
+				}
+			}
+			if err := storePostCategories(ctx, q, post.ID, item.Categories); err != nil {
+				return fmt.Errorf("could not store post categories: %w", err)
 			}
-			fmt.Printf("Couldn't create post: %v", err)
+			if err := storePostEnclosures(ctx, q, post.ID, item.Enclosures); err != nil {
+				return fmt.Errorf("could not store post enclosures: %w", err)
+			}
+			return nil
+		})
+		if err == sql.ErrNoRows {
+			scraped.Skipped++
+			continue
+		} else if err != nil {
+			fmt.Printf("Couldn't store post: %v\n", err)
+			scraped.Errored++
 			continue
 		}
+
+		if post.ID == postID && replaced == 0 {
+			scraped.Inserted++
+		} else {
+			scraped.Updated++
+		}
+	}
+
+	// Validators are only stored once every post is in, so a failed run
+	// is not hidden behind a 304 on the next fetch.
+	if scraped.Errored == 0 {
+		cacheParams := database.SetFeedCacheValidatorsParams{
+			ID:           feed.ID,
+			Etag:         sql.NullString{String: result.Validators.ETag, Valid: result.Validators.ETag != ""},
+			LastModified: sql.NullString{String: result.Validators.LastModified, Valid: result.Validators.LastModified != ""},
+		}
+		if err := s.Db.SetFeedCacheValidators(ctx, cacheParams); err != nil {
+			fmt.Printf("could not store cache validators for feed: %v\n", err)
+		}
+	}
+
+	warningParams := database.SetFeedParseWarningParams{
+		ID:           feed.ID,
+		ParseWarning: sql.NullString{String: result.Warning, Valid: result.Warning != ""},
+	}
+	if err := s.Db.SetFeedParseWarning(ctx, warningParams); err != nil {
+		fmt.Printf("could not store parse warning for feed: %v\n", err)
+	}
+
+	return scraped, nil
+}
+
+// storePostCategories replaces the post's categories with the item's, so a
+// revision that drops a tag also drops it here.
+func storePostCategories(ctx context.Context, q *database.Queries, postID uuid.UUID, categories []string) error {
+	if err := q.DeletePostCategories(ctx, postID); err != nil {
+		return err
+	}
+	for _, category := range categories {
+		params := database.AddPostCategoryParams{PostID: postID, Name: category}
+		if err := q.AddPostCategory(ctx, params); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// storePostEnclosures records the item's enclosures. Ones the item no longer
+// lists are kept, along with anything already downloaded for them.
+func storePostEnclosures(ctx context.Context, q *database.Queries, postID uuid.UUID, enclosures []RSSEnclosure) error {
+	for _, enclosure := range enclosures {
+		length, err := strconv.ParseInt(enclosure.Length, 10, 64)
+		params := database.UpsertPostEnclosureParams{
+			ID:       uuid.New(),
+			PostID:   postID,
+			Url:      enclosure.URL,
+			MimeType: sql.NullString{String: enclosure.Type, Valid: enclosure.Type != ""},
+			Length:   sql.NullInt64{Int64: length, Valid: err == nil && length > 0},
+		}
+		if err := q.UpsertPostEnclosure(ctx, params); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// aggregateStats sums up the scrapes of an agg run across its workers.
+type aggregateStats struct {
+	mu          sync.Mutex
+	fetched     int
+	notModified int
+	failed      int
+	inserted    int
+	updated     int
+	skipped     int
+	errored     int
+}
+
+func (a *aggregateStats) record(feed database.Feed, result scrapeResult, err error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	switch {
+	case err != nil:
+		a.failed++
+		fmt.Println(err)
+	case result.NotModified:
+		a.notModified++
+		fmt.Printf("Feed %s not modified\n", feed.Name)
+	default:
+		a.fetched++
+		a.inserted += result.Inserted
+		a.updated += result.Updated
+		a.skipped += result.Skipped
+		a.errored += result.Errored
+		fmt.Printf("Feed %s collected, %v posts found\n", feed.Name, result.Seen)
+		if result.Warning != "" {
+			fmt.Printf("  warning: %s\n", result.Warning)
+		}
 	}
-	fmt.Printf("Feed %s collected, %v posts found\n", feed.Name, len(rssFeed.Channel.Item))
+}
+
+func (a *aggregateStats) String() string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	return fmt.Sprintf("feeds: %d collected, %d not modified, %d failed; posts: %d inserted, %d updated, %d skipped, %d errored",
+		a.fetched, a.notModified, a.failed, a.inserted, a.updated, a.skipped, a.errored)
+}
+
+func (a *aggregateStats) Failed() int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	return a.failed
 }
 
 // User Handlers
-func HandlerLogin(s *State, cmd Command) error {
+func HandlerLogin(ctx context.Context, s *State, cmd Command) error {
 	if len(cmd.Args) != 1 {
 		return fmt.Errorf("usage: %s <name>", cmd.Name)
 	}
 	name := cmd.Args[0]
-	ctx := context.Background()
 
 	_, err := s.Db.GetUserByName(ctx, name)
 	if err == sql.ErrNoRows {
@@ -84,12 +371,11 @@ func HandlerLogin(s *State, cmd Command) error {
 	return nil
 }
 
-func HandlerRegister(s *State, cmd Command) error {
+func HandlerRegister(ctx context.Context, s *State, cmd Command) error {
 	if len(cmd.Args) != 1 {
 		return fmt.Errorf("usage: %s <name>", cmd.Name)
 	}
 	
-	ctx := context.Background()
 	name := cmd.Args[0]
 
 	_, err := s.Db.GetUserByName(ctx, name)
@@ -121,10 +407,15 @@ func HandlerRegister(s *State, cmd Command) error {
 	return nil
 }
 
-func HandlerReset(s *State, cmd Command) error {
-	ctx := context.Background()
-	if err := s.Db.DropUsers(ctx); err != nil {
-		return fmt.Errorf("failed to truncate users table: %w", err)
+func HandlerReset(ctx context.Context, s *State, cmd Command) error {
+	err := s.withTx(ctx, func(q *database.Queries) error {
+		if err := q.DropUsers(ctx); err != nil {
+			return fmt.Errorf("failed to truncate users table: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
 	}
 
 	fmt.Println("Users table reset successully")
@@ -132,8 +423,7 @@ func HandlerReset(s *State, cmd Command) error {
 	return nil
 }
 
-func HandlerGetUsers(s *State, cmd Command) error {
-	ctx := context.Background()
+func HandlerGetUsers(ctx context.Context, s *State, cmd Command) error {
 	currUserName := s.Cfg.CurrentUserName
 	if currUserName == "" {
 		return fmt.Errorf("no users found, please register a user")
@@ -156,66 +446,268 @@ func HandlerGetUsers(s *State, cmd Command) error {
 }
 
 // Feed Handlers
-func HandlerAggregate(s *State, cmd Command) error {
-	if len(cmd.Args) != 1 {
-		return fmt.Errorf("usage: %s <time_interval>(ex. 1m, or 1h)", cmd.Name)
+func HandlerAggregate(ctx context.Context, s *State, cmd Command) error {
+	fs := flag.NewFlagSet(cmd.Name, flag.ContinueOnError)
+	concurrency := fs.Int("concurrency", 1, "number of feeds fetched in parallel")
+	once := fs.Bool("once", false, "fetch every due feed once, then exit")
+	feedURL := fs.String("feed", "", "fetch only this feed, right away")
+	args, err := parseFlags(fs, cmd.Args)
+	if err != nil {
+		return err
 	}
 
-	ctx := context.Background()
-	timeBetweenRequests, err := time.ParseDuration(cmd.Args[0])
-	if err != nil {
-		return fmt.Errorf("error parsing time duration: %w", err)
+	usage := fmt.Errorf("usage: %s [--concurrency N] <time_interval>(ex. 1m, or 1h) | --once [--concurrency N] | --feed <url>", cmd.Name)
+	if *concurrency < 1 {
+		return usage
+	}
+
+	var stats aggregateStats
+	switch {
+	case *feedURL != "":
+		if len(args) != 0 || *once {
+			return usage
+		}
+		if err := aggregateFeed(ctx, s, *feedURL, &stats); err != nil {
+			return err
+		}
+	case *once:
+		if len(args) != 0 {
+			return usage
+		}
+		aggregateOnce(ctx, s, *concurrency, &stats)
+	default:
+		if len(args) != 1 {
+			return usage
+		}
+		timeBetweenRequests, err := time.ParseDuration(args[0])
+		if err != nil {
+			return fmt.Errorf("error parsing time duration: %w", err)
+		}
+		// Each worker's share of the interval has to be a positive duration.
+		if timeBetweenRequests < time.Duration(*concurrency) {
+			return usage
+		}
+		aggregateForever(ctx, s, timeBetweenRequests, *concurrency, &stats)
 	}
 
-	ticker := time.NewTicker(timeBetweenRequests)
+	fmt.Printf("\nStopped collecting feeds: %s\n", &stats)
+	if failed := stats.Failed(); failed > 0 && (*once || *feedURL != "") {
+		return fmt.Errorf("%d feed(s) failed", failed)
+	}
+	return nil
+}
+
+func aggregateForever(ctx context.Context, s *State, timeBetweenRequests time.Duration, concurrency int, stats *aggregateStats) {
+	// All workers share one ticker, so no more than concurrency feeds are
+	// requested per interval however many of them are in flight.
+	ticker := time.NewTicker(timeBetweenRequests / time.Duration(concurrency))
+	defer ticker.Stop()
+
+	fmt.Printf("Collecting feeds every %s with %d worker(s)\n", timeBetweenRequests, concurrency)
+
+	var wg sync.WaitGroup
+	for range concurrency {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				if feed, ok := claimNextFeed(ctx, s); ok {
+					runScrape(ctx, s, feed, stats)
+				}
+				select {
+				case <-ctx.Done():
+					return
+				case <-ticker.C:
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
 
-	fmt.Printf("Collecting feeds every 1m0s")
-	for ; ; <- ticker.C {
-		scrapeFeeds(s, ctx)
+// aggregateOnce makes a single pass over the feeds that are due. A feed
+// fetched earlier in the pass can come due again before the pass ends, so
+// meeting one means everything else has been claimed.
+func aggregateOnce(ctx context.Context, s *State, concurrency int, stats *aggregateStats) {
+	fmt.Printf("Collecting due feeds with %d worker(s)\n", concurrency)
+
+	var seen sync.Map
+	var wg sync.WaitGroup
+	for range concurrency {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				feed, ok := claimNextFeed(ctx, s)
+				if !ok {
+					return
+				}
+				if _, fetched := seen.LoadOrStore(feed.ID, true); fetched {
+					if err := s.Db.ReleaseFeedLease(ctx, feed.ID); err != nil {
+						fmt.Printf("could not release feed %s: %v\n", feed.Url, err)
+					}
+					return
+				}
+				runScrape(ctx, s, feed, stats)
+			}
+		}()
 	}
+	wg.Wait()
+}
 
+func aggregateFeed(ctx context.Context, s *State, feedURL string, stats *aggregateStats) error {
+	feed, err := s.Db.GetFeedByUrl(ctx, feedURL)
+	if err != nil {
+		return fmt.Errorf("could not get feed by url: %w", err)
+	}
+
+	args := database.ClaimFeedParams{
+		ID:           feed.ID,
+		LeaseSeconds: int32(feedLease.Seconds()),
+	}
+	feed, err = s.Db.ClaimFeed(ctx, args)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("feed %s is being fetched by another worker", feedURL)
+	} else if err != nil {
+		return fmt.Errorf("could not claim feed: %w", err)
+	}
+
+	runScrape(ctx, s, feed, stats)
 	return nil
 }
 
-var HandlerAddFeed = func(s *State, cmd Command, user database.User) error {
-	if len(cmd.Args) != 2 {
-		return fmt.Errorf("usage: %s <name> <url>", cmd.Name)	
+// claimNextFeed leases the next due feed, reporting false when none is due
+// or ctx is done.
+func claimNextFeed(ctx context.Context, s *State) (database.Feed, bool) {
+	if ctx.Err() != nil {
+		return database.Feed{}, false
 	}
 
-	ctx := context.Background()
+	feed, err := s.Db.ClaimNextFeed(ctx, int32(feedLease.Seconds()))
+	if err == sql.ErrNoRows || ctx.Err() != nil {
+		return database.Feed{}, false
+	} else if err != nil {
+		fmt.Printf("error claiming the next feed: %v\n", err)
+		return database.Feed{}, false
+	}
 
-	now := time.Now()
-	args := database.AddFeedParams{
-		ID: uuid.New(),
-		Name: cmd.Args[0],
-		UserID: user.ID,
-		Url: cmd.Args[1],
-		CreatedAt: now,
-		UpdatedAt: now,
+	return feed, true
+}
+
+// runScrape scrapes a claimed feed. The scrape is shielded from
+// cancellation, so shutting down lets in-flight feeds finish and release
+// their lease instead of abandoning them midway.
+func runScrape(ctx context.Context, s *State, feed database.Feed, stats *aggregateStats) {
+	scrapeCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), feedLease)
+	defer cancel()
+
+	result, err := scrapeFeed(scrapeCtx, s, feed)
+	stats.record(feed, result, err)
+}
+
+var HandlerAddFeed = func(ctx context.Context, s *State, cmd Command, user database.User) error {
+	fs := flag.NewFlagSet(cmd.Name, flag.ContinueOnError)
+	interval := fs.String("interval", "default", "how often to poll the feed (ex. 1m, or 168h)")
+	noVerify := fs.Bool("no-verify", false, "add the feed without fetching it first")
+	cmdArgs, err := parseFlags(fs, cmd.Args)
+	if err != nil {
+		return err
+	}
+
+	var name, feedURL string
+	switch len(cmdArgs) {
+	case 1:
+		feedURL = cmdArgs[0]
+	case 2:
+		name, feedURL = cmdArgs[0], cmdArgs[1]
+	default:
+		return fmt.Errorf("usage: %s [--no-verify] [--interval <duration>] [<name>] <url>", cmd.Name)
+	}
+	if *noVerify && name == "" {
+		return errors.New("a name is required with --no-verify, since the feed is not fetched for its title")
 	}
 
-	feed, err := s.Db.AddFeed(ctx, args)
+	pollInterval, err := parsePollInterval(*interval)
 	if err != nil {
-		return fmt.Errorf("could not add feed: %w", err)
+		return err
 	}
 
-	followParams := database.CreateFeedFollowParams{
+	var siteLink, description string
+	if !*noVerify {
+		verified, err := verifyFeed(ctx, s, feedURL)
+		if isWebPage(err) {
+			feedURL, err = resolveFeedURL(ctx, s, feedURL)
+			if err != nil {
+				return err
+			}
+			verified, err = verifyFeed(ctx, s, feedURL)
+		}
+		if err != nil {
+			return err
+		}
+		if name == "" {
+			name = strings.TrimSpace(verified.Channel.Title)
+		}
+		siteLink = strings.TrimSpace(verified.Channel.Link)
+		description = strings.TrimSpace(verified.Channel.Description)
+	}
+	if name == "" {
+		return fmt.Errorf("feed %s has no title, give it a name", feedURL)
+	}
+
+	if existing, err := s.Db.GetFeedByUrl(ctx, feedURL); err == nil {
+		return fmt.Errorf("feed %s already exists as %s, follow it instead", existing.Name, existing.Url)
+	} else if err != sql.ErrNoRows {
+		return fmt.Errorf("could not get feed by url: %w", err)
+	}
+
+	now := time.Now()
+	args := database.AddFeedParams{
 		ID: uuid.New(),
+		Name: name,
+		UserID: user.ID,
+		Url: feedURL,
 		CreatedAt: now,
 		UpdatedAt: now,
-		UserID: user.ID,
-		FeedID: feed.ID,
+		PollInterval: pollInterval,
+		SiteLink: sql.NullString{String: siteLink, Valid: siteLink != ""},
+		Description: sql.NullString{String: description, Valid: description != ""},
 	}
 
-	followed, err := s.Db.CreateFeedFollow(ctx, followParams)
+	var feed database.Feed
+	var followed database.CreateFeedFollowRow
+	err = s.withTx(ctx, func(q *database.Queries) error {
+		var err error
+		feed, err = q.AddFeed(ctx, args)
+		if err != nil {
+			return fmt.Errorf("could not add feed: %w", err)
+		}
+
+		followParams := database.CreateFeedFollowParams{
+			ID: uuid.New(),
+			CreatedAt: now,
+			UpdatedAt: now,
+			UserID: user.ID,
+			FeedID: feed.ID,
+		}
+
+		followed, err = q.CreateFeedFollow(ctx, followParams)
+		if err != nil {
+			return fmt.Errorf("could not follow the feed: %w", err)
+		}
+		return nil
+	})
 	if err != nil {
-		return fmt.Errorf("could not follow the feed: %w", err)
+		return err
 	}
 
 	fmt.Printf("Feed created:\n")
 	fmt.Printf("ID:        %s\n", feed.ID)
 	fmt.Printf("Name:      %s\n", feed.Name)
 	fmt.Printf("URL:       %s\n", feed.Url)
+	if feed.SiteLink.Valid {
+		fmt.Printf("Site:      %s\n", feed.SiteLink.String)
+	}
 	fmt.Printf("UserID:    %s\n", feed.UserID)
 
 	fmt.Printf("\n%s now follows %s\n", followed.UserName, followed.FeedName)
@@ -223,9 +715,34 @@ var HandlerAddFeed = func(s *State, cmd Command, user database.User) error {
 	return nil
 }
 
-func HandlerGetFeeds(s *State, cmd Command) error {
-	ctx := context.Background()
-	
+// verifyFeed fetches and parses feedURL, so a feed that could never be
+// collected is rejected before it is stored.
+func verifyFeed(ctx context.Context, s *State, feedURL string) (*RSSFeed, error) {
+	result, err := s.Fetcher.FetchFeed(ctx, feedURL, CacheValidators{})
+	if err != nil {
+		return nil, fmt.Errorf("could not verify feed %s: %w", feedURL, err)
+	}
+	if result.Warning != "" {
+		fmt.Printf("Warning: %s\n", result.Warning)
+	}
+	return result.Feed, nil
+}
+
+func HandlerGetFeeds(ctx context.Context, s *State, cmd Command) error {
+	fs := flag.NewFlagSet(cmd.Name, flag.ContinueOnError)
+	broken := fs.Bool("broken", false, "list only failing and disabled feeds")
+	args, err := parseFlags(fs, cmd.Args)
+	if err != nil {
+		return err
+	}
+	if len(args) != 0 {
+		return fmt.Errorf("usage: %s [--broken]", cmd.Name)
+	}
+
+	if *broken {
+		return listBrokenFeeds(ctx, s)
+	}
+
 	feeds, err := s.Db.GetFeeds(ctx)
 	if err != nil {
 		return fmt.Errorf("could not get feeds: %w", err)
@@ -238,15 +755,173 @@ func HandlerGetFeeds(s *State, cmd Command) error {
 	return nil
 }
 
+func listBrokenFeeds(ctx context.Context, s *State) error {
+	feeds, err := s.Db.GetBrokenFeeds(ctx)
+	if err != nil {
+		return fmt.Errorf("could not get broken feeds: %w", err)
+	}
+
+	if len(feeds) == 0 {
+		fmt.Println("No broken feeds")
+		return nil
+	}
+
+	for _, feed := range feeds {
+		fmt.Printf("Name: %s\nURL: %s\n", feed.Name, feed.Url)
+		if feed.PollInterval.Valid {
+			fmt.Printf("Poll interval: %s\n", time.Duration(feed.PollInterval.Int32)*time.Second)
+		}
+		fmt.Printf("Consecutive failures: %d\n", feed.ConsecutiveFailures)
+		if feed.ParseWarning.Valid {
+			fmt.Printf("Parse warning: %s\n", feed.ParseWarning.String)
+		}
+		if feed.DisabledAt.Valid {
+			fmt.Printf("Disabled: %s\n", feed.DisabledReason.String)
+		} else {
+			fmt.Printf("Retrying at: %s\n", feed.NextFetchAt.Time.Format(time.DateTime))
+		}
+	}
+
+	return nil
+}
+
+func HandlerFeedEnable(ctx context.Context, s *State, cmd Command) error {
+	if len(cmd.Args) != 1 {
+		return fmt.Errorf("usage: %s <url>", cmd.Name)
+	}
+
+	feed, err := s.Db.GetFeedByUrl(ctx, cmd.Args[0])
+	if err != nil {
+		return fmt.Errorf("could not get feed by url: %w", err)
+	}
+
+	if err := s.Db.EnableFeed(ctx, feed.ID); err != nil {
+		return fmt.Errorf("could not enable feed: %w", err)
+	}
+
+	fmt.Printf("Feed %s enabled, it will be fetched on the next agg tick\n", feed.Name)
+	return nil
+}
+
+func HandlerFeedSet(ctx context.Context, s *State, cmd Command) error {
+	if len(cmd.Args) != 3 {
+		return fmt.Errorf("usage: %s <url> interval <duration|default>", cmd.Name)
+	}
+	url, setting, value := cmd.Args[0], cmd.Args[1], cmd.Args[2]
+
+	feed, err := s.Db.GetFeedByUrl(ctx, url)
+	if err != nil {
+		return fmt.Errorf("could not get feed by url: %w", err)
+	}
+
+	switch setting {
+	case "interval":
+		pollInterval, err := parsePollInterval(value)
+		if err != nil {
+			return err
+		}
+
+		args := database.SetFeedPollIntervalParams{
+			ID:           feed.ID,
+			PollInterval: pollInterval,
+		}
+		if err := s.Db.SetFeedPollInterval(ctx, args); err != nil {
+			return fmt.Errorf("could not set poll interval: %w", err)
+		}
+	default:
+		return fmt.Errorf("unknown feed setting %q, expected interval", setting)
+	}
+
+	fmt.Printf("Feed %s: %s set to %s\n", feed.Name, setting, value)
+	return nil
+}
+
+// feedStatusHistory is how many fetch attempts feed-status lists.
+const feedStatusHistory = 10
+
+func HandlerFeedStatus(ctx context.Context, s *State, cmd Command) error {
+	if len(cmd.Args) != 1 {
+		return fmt.Errorf("usage: %s <url>", cmd.Name)
+	}
+
+	feed, err := s.Db.GetFeedByUrl(ctx, cmd.Args[0])
+	if err != nil {
+		return fmt.Errorf("could not get feed by url: %w", err)
+	}
+
+	fetches, err := s.Db.GetFeedFetches(ctx, database.GetFeedFetchesParams{
+		FeedID: feed.ID,
+		Limit:  feedStatusHistory,
+	})
+	if err != nil {
+		return fmt.Errorf("could not get feed fetches: %w", err)
+	}
+
+	fmt.Printf("Name:                 %s\n", feed.Name)
+	fmt.Printf("URL:                  %s\n", feed.Url)
+	if feed.SiteLink.Valid {
+		fmt.Printf("Site:                 %s\n", feed.SiteLink.String)
+	}
+	if feed.Description.Valid {
+		fmt.Printf("Description:          %s\n", feed.Description.String)
+	}
+	aliases, err := s.Db.GetFeedAliases(ctx, feed.ID)
+	if err != nil {
+		return fmt.Errorf("could not get feed aliases: %w", err)
+	}
+	for _, alias := range aliases {
+		fmt.Printf("Moved from:           %s\n", alias)
+	}
+	if feed.PollInterval.Valid {
+		fmt.Printf("Poll interval:        %s\n", time.Duration(feed.PollInterval.Int32)*time.Second)
+	}
+	fmt.Printf("Consecutive failures: %d\n", feed.ConsecutiveFailures)
+	if feed.ParseWarning.Valid {
+		fmt.Printf("Parse warning:        %s\n", feed.ParseWarning.String)
+	}
+	if feed.DisabledAt.Valid {
+		fmt.Printf("Disabled:             %s (%s)\n", feed.DisabledAt.Time.Format(time.DateTime), feed.DisabledReason.String)
+	} else if feed.NextFetchAt.Valid {
+		fmt.Printf("Next fetch:           %s\n", feed.NextFetchAt.Time.Format(time.DateTime))
+	}
+
+	fmt.Printf("\nRecent attempts:\n")
+	if len(fetches) == 0 {
+		fmt.Println("  none yet")
+	}
+	for _, fetch := range fetches {
+		status := "---"
+		if fetch.StatusCode.Valid {
+			status = strconv.Itoa(int(fetch.StatusCode.Int32))
+		}
+		fmt.Printf("* %s  %s  %5dms  %8d bytes  %d seen, %d new\n",
+			fetch.FetchedAt.Format(time.DateTime), status, fetch.DurationMs, fetch.Bytes, fetch.ItemsSeen, fetch.ItemsInserted)
+		if fetch.Error.Valid {
+			fmt.Printf("    error: %s\n", fetch.Error.String)
+		}
+	}
+
+	return nil
+}
+
 // Follow Handlers
-var HandlerFollowFeed = func(s *State, cmd Command, user database.User) error {
+var HandlerFollowFeed = func(ctx context.Context, s *State, cmd Command, user database.User) error {
 	if len(cmd.Args) != 1 {
 		return fmt.Errorf("usage: %s <url>", cmd.Name)	
 	}
 
-	ctx := context.Background()
-
 	feed, err := s.Db.GetFeedByUrl(ctx, cmd.Args[0])
+	if err == sql.ErrNoRows {
+		// Maybe it is the address of a website whose feed we know.
+		feedURL, resolveErr := resolveFeedURL(ctx, s, cmd.Args[0])
+		if resolveErr != nil {
+			return resolveErr
+		}
+		feed, err = s.Db.GetFeedByUrl(ctx, feedURL)
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("no feed with url %s yet, add it with addfeed", feedURL)
+		}
+	}
 	if err != nil {
 		return fmt.Errorf("could not get feed by url: %w", err)
 	}
@@ -269,9 +944,7 @@ var HandlerFollowFeed = func(s *State, cmd Command, user database.User) error {
 	return nil
 }
 
-var HandlerFollowing = func(s *State, cmd Command, user database.User) error {
-	ctx := context.Background()
-
+var HandlerFollowing = func(ctx context.Context, s *State, cmd Command, user database.User) error {
 	feeds, err := s.Db.GetFeedFollowsForUser(ctx, user.ID)
 	if err != nil {
 		return fmt.Errorf("could not get feed follows for user: %w", err)
@@ -279,20 +952,22 @@ var HandlerFollowing = func(s *State, cmd Command, user database.User) error {
 
 	fmt.Printf("Feeds followed from %s\n", user.Name)
 	for _, feed := range feeds {
-		fmt.Printf("%s", feed.FeedName)
+		if feed.Category.Valid {
+			fmt.Printf("* %s [%s]\n", feed.FeedName, feed.Category.String)
+		} else {
+			fmt.Printf("* %s\n", feed.FeedName)
+		}
 	}
 
 	return nil
 
 }
 
-var HandlerUnfollow = func(s *State, cmd Command, user database.User) error {
+var HandlerUnfollow = func(ctx context.Context, s *State, cmd Command, user database.User) error {
 	if len(cmd.Args) != 1 {
 		return fmt.Errorf("usage: %s <url>", cmd.Name)
 	}
 
-	ctx := context.Background()
-
 	args := database.DeleteFeedFollowByUserAndUrlParams{
 		UserID: user.ID,
 		Url: cmd.Args[0],
@@ -309,13 +984,22 @@ var HandlerUnfollow = func(s *State, cmd Command, user database.User) error {
 
 }
 
-var HandlerBrowse = func(s *State, cmd Command, user database.User) error {
+var HandlerBrowse = func(ctx context.Context, s *State, cmd Command, user database.User) error {
+	fs := flag.NewFlagSet(cmd.Name, flag.ContinueOnError)
+	updated := fs.Bool("updated", false, "show only posts edited since they were first seen")
+	category := fs.String("category", "", "show only posts tagged with this category")
+	full := fs.Bool("full", false, "show the full content of posts that have it")
+	cmdArgs, err := parseFlags(fs, cmd.Args)
+	if err != nil {
+		return err
+	}
+
 	var limit int
-	switch len(cmd.Args) {
+	switch len(cmdArgs) {
 	case 0:
 		limit = 2
 	case 1:
-		l, err := strconv.Atoi(cmd.Args[0])
+		l, err := strconv.Atoi(cmdArgs[0])
 		if err != nil || l <= 0 {
 			return fmt.Errorf("invalid limit. Must be non-zero positive number: %w", err)
 		}
@@ -324,9 +1008,10 @@ var HandlerBrowse = func(s *State, cmd Command, user database.User) error {
 		return errors.New("too many arguments. provide only an optional limit")
 	}
 
-	ctx := context.Background()
 	args := database.GetPostsForUserParams{
 		UserID: user.ID,
+		UpdatedOnly: *updated,
+		Category: *category,
 		Limit: int32(limit),
 	}
 
@@ -337,9 +1022,37 @@ var HandlerBrowse = func(s *State, cmd Command, user database.User) error {
 
 	fmt.Printf("Found %d posts for user %s:\n", len(posts), user.Name)
 	for _, post := range posts {
-		fmt.Printf("%s from %s\n", post.PublishedAt.Format("Mon Jan 2"), post.FeedName)
+		published := post.PublishedAt.Format("Mon Jan 2")
+		if post.PublishedAtEstimated {
+			published += " (estimated)"
+		}
+		fmt.Printf("%s from %s\n", published, post.FeedName)
+		if post.Revisions > 0 {
+			fmt.Printf("(updated %s, %d revision(s))\n", post.UpdatedAt.Format("Mon Jan 2"), post.Revisions)
+		}
 		fmt.Printf("--- %s ---\n", post.Title)
-		fmt.Printf("    %v\n", post.Description.String)
+		if post.Author.Valid {
+			fmt.Printf("By %s\n", post.Author.String)
+		}
+		categories, err := s.Db.GetPostCategories(ctx, post.ID)
+		if err != nil {
+			return fmt.Errorf("couldn't get categories for post: %w", err)
+		}
+		if len(categories) > 0 {
+			fmt.Printf("Categories: %s\n", strings.Join(categories, ", "))
+		}
+		enclosures, err := s.Db.GetPostEnclosures(ctx, post.ID)
+		if err != nil {
+			return fmt.Errorf("couldn't get enclosures for post: %w", err)
+		}
+		for _, enclosure := range enclosures {
+			fmt.Printf("Enclosure: %s%s\n", enclosure.Url, enclosureDetails(enclosure))
+		}
+		if *full && post.Content.Valid {
+			fmt.Printf("    %v\n", post.Content.String)
+		} else {
+			fmt.Printf("    %v\n", post.Description.String)
+		}
 		fmt.Printf("Link: %s\n", post.Url)
 		fmt.Println("=====================================")
 	}
@@ -0,0 +1,28 @@
+package app
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFeedBackoff(t *testing.T) {
+	tests := []struct {
+		failures int32
+		want     time.Duration
+	}{
+		{0, 5 * time.Minute},
+		{1, 5 * time.Minute},
+		{2, 10 * time.Minute},
+		{3, 20 * time.Minute},
+		{5, 80 * time.Minute},
+		{9, 21*time.Hour + 20*time.Minute},
+		{10, 24 * time.Hour},
+		{1000, 24 * time.Hour},
+	}
+
+	for _, tt := range tests {
+		if got := feedBackoff(tt.failures); got != tt.want {
+			t.Errorf("feedBackoff(%d) = %v, want %v", tt.failures, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package app
+
+import (
+	"strconv"
+	"strings"
+)
+
+// jsonFeedVersionPrefix starts the version url that every JSON Feed declares.
+const jsonFeedVersionPrefix = "https://jsonfeed.org/version/"
+
+type JSONFeed struct {
+	Version     string         `json:"version"`
+	Title       string         `json:"title"`
+	HomePageURL string         `json:"home_page_url"`
+	Description string         `json:"description"`
+	Items       []JSONFeedItem `json:"items"`
+}
+
+type JSONFeedItem struct {
+	ID            string               `json:"id"`
+	URL           string               `json:"url"`
+	ExternalURL   string               `json:"external_url"`
+	Title         string               `json:"title"`
+	ContentHTML   string               `json:"content_html"`
+	ContentText   string               `json:"content_text"`
+	Summary       string               `json:"summary"`
+	DatePublished string               `json:"date_published"`
+	DateModified  string               `json:"date_modified"`
+	Tags          []string             `json:"tags"`
+	Attachments   []JSONFeedAttachment `json:"attachments"`
+	Authors       []JSONFeedAuthor     `json:"authors"`
+	// Author is the JSON Feed 1.0 form, superseded by Authors in 1.1.
+	Author *JSONFeedAuthor `json:"author"`
+}
+
+type JSONFeedAttachment struct {
+	URL         string `json:"url"`
+	MimeType    string `json:"mime_type"`
+	SizeInBytes int64  `json:"size_in_bytes"`
+}
+
+type JSONFeedAuthor struct {
+	Name string `json:"name"`
+	URL  string `json:"url"`
+}
+
+// jsonFeedTitleLength bounds the title derived from an untitled item's text.
+const jsonFeedTitleLength = 80
+
+func (j *JSONFeed) toRSS() *RSSFeed {
+	var rssFeed RSSFeed
+	rssFeed.Channel.Title = j.Title
+	rssFeed.Channel.Link = j.HomePageURL
+	rssFeed.Channel.Description = j.Description
+
+	for _, item := range j.Items {
+		link := item.URL
+		if link == "" {
+			link = item.ExternalURL
+		}
+
+		description := item.Summary
+		if description == "" {
+			description = item.ContentHTML
+		}
+		if description == "" {
+			description = item.ContentText
+		}
+
+		pubDate := item.DatePublished
+		if pubDate == "" {
+			pubDate = item.DateModified
+		}
+
+		authors := item.Authors
+		if len(authors) == 0 && item.Author != nil {
+			authors = []JSONFeedAuthor{*item.Author}
+		}
+		var names []string
+		for _, author := range authors {
+			if author.Name != "" {
+				names = append(names, author.Name)
+			}
+		}
+
+		content := item.ContentHTML
+		if content == "" {
+			content = item.ContentText
+		}
+
+		var enclosures []RSSEnclosure
+		for _, attachment := range item.Attachments {
+			enclosure := RSSEnclosure{URL: attachment.URL, Type: attachment.MimeType}
+			if attachment.SizeInBytes > 0 {
+				enclosure.Length = strconv.FormatInt(attachment.SizeInBytes, 10)
+			}
+			enclosures = append(enclosures, enclosure)
+		}
+
+		rssFeed.Channel.Item = append(rssFeed.Channel.Item, RSSItem{
+			GUID:        item.ID,
+			Title:       jsonFeedItemTitle(item),
+			Link:        link,
+			Description: description,
+			PubDate:     pubDate,
+			Author:      strings.Join(names, ", "),
+			Content:     content,
+			Categories:  item.Tags,
+			Enclosures:  enclosures,
+		})
+	}
+
+	return &rssFeed
+}
+
+// jsonFeedItemTitle falls back to the start of the item's text, since title
+// is optional in JSON Feed and microblogs routinely leave it out.
+func jsonFeedItemTitle(item JSONFeedItem) string {
+	if item.Title != "" {
+		return item.Title
+	}
+
+	text := strings.Join(strings.Fields(item.ContentText), " ")
+	if text == "" {
+		text = strings.Join(strings.Fields(item.Summary), " ")
+	}
+	if runes := []rune(text); len(runes) > jsonFeedTitleLength {
+		return string(runes[:jsonFeedTitleLength]) + "..."
+	}
+	return text
+}
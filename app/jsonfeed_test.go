@@ -0,0 +1,100 @@
+package app
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseFeedJSON(t *testing.T) {
+	data := []byte(`{
+		"version": "https://jsonfeed.org/version/1.1",
+		"title": "Example",
+		"home_page_url": "https://example.com/",
+		"description": "A JSON feed",
+		"items": [
+			{
+				"id": "1",
+				"url": "https://example.com/1",
+				"title": "First",
+				"summary": "Short",
+				"content_html": "<p>Long</p>",
+				"date_published": "2006-01-02T15:04:05Z",
+				"tags": ["Go", "News"],
+				"authors": [{"name": "Jane"}, {"name": ""}, {"name": "John"}],
+				"attachments": [
+					{"url": "https://example.com/1.mp3", "mime_type": "audio/mpeg", "size_in_bytes": 1234},
+					{"url": "https://example.com/1.txt", "mime_type": "text/plain"}
+				]
+			},
+			{
+				"id": "2",
+				"external_url": "https://elsewhere.com/2",
+				"content_text": "Just a short note without a title",
+				"date_modified": "2006-01-03T00:00:00Z",
+				"author": {"name": "Old style"}
+			}
+		]
+	}`)
+
+	feed, err := parseFeed(data, "application/feed+json")
+	if err != nil {
+		t.Fatalf("parseFeed returned error: %v", err)
+	}
+
+	if feed.Channel.Title != "Example" || feed.Channel.Link != "https://example.com/" || feed.Channel.Description != "A JSON feed" {
+		t.Errorf("got channel %q, %q, %q", feed.Channel.Title, feed.Channel.Link, feed.Channel.Description)
+	}
+
+	want := []RSSItem{
+		{
+			GUID:        "1",
+			Title:       "First",
+			Link:        "https://example.com/1",
+			Description: "Short",
+			PubDate:     "2006-01-02T15:04:05Z",
+			Author:      "Jane, John",
+			Content:     "<p>Long</p>",
+			Categories:  []string{"Go", "News"},
+			Enclosures: []RSSEnclosure{
+				{URL: "https://example.com/1.mp3", Type: "audio/mpeg", Length: "1234"},
+				{URL: "https://example.com/1.txt", Type: "text/plain"},
+			},
+		},
+		{
+			GUID:        "2",
+			Title:       "Just a short note without a title",
+			Link:        "https://elsewhere.com/2",
+			Description: "Just a short note without a title",
+			PubDate:     "2006-01-03T00:00:00Z",
+			Author:      "Old style",
+			Content:     "Just a short note without a title",
+		},
+	}
+	if !reflect.DeepEqual(feed.Channel.Item, want) {
+		t.Errorf("items = %+v\nwant %+v", feed.Channel.Item, want)
+	}
+}
+
+func TestJSONFeedItemTitle(t *testing.T) {
+	long := strings.Repeat("word ", 30)
+	tests := []struct {
+		name string
+		item JSONFeedItem
+		want string
+	}{
+		{"title", JSONFeedItem{Title: "Title", ContentText: "text"}, "Title"},
+		{"content text", JSONFeedItem{ContentText: "  some\n text  "}, "some text"},
+		{"summary", JSONFeedItem{Summary: "summary", ContentHTML: "<p>html</p>"}, "summary"},
+		{"truncated", JSONFeedItem{ContentText: long}, strings.TrimSpace(long)[:jsonFeedTitleLength] + "..."},
+		{"nothing", JSONFeedItem{ContentHTML: "<p>html</p>"}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := jsonFeedItemTitle(tt.item); got != tt.want {
+				t.Errorf("jsonFeedItemTitle = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,52 @@
+package app
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+)
+
+var utf8BOM = []byte("\xef\xbb\xbf")
+
+// newLenientXMLDecoder accepts the mistakes real feeds make: HTML entities
+// such as &nbsp;, bare ampersands, unquoted attributes and unclosed HTML
+// tags.
+func newLenientXMLDecoder(data []byte) *xml.Decoder {
+	decoder := newXMLDecoder(data)
+	decoder.Strict = false
+	decoder.AutoClose = xml.HTMLAutoClose
+	decoder.Entity = xml.HTMLEntity
+	return decoder
+}
+
+// sanitizeXML drops a byte order mark and the characters XML does not
+// allow, such as stray control characters. Invalid UTF-8 becomes U+FFFD.
+func sanitizeXML(data []byte) []byte {
+	data = bytes.TrimPrefix(data, utf8BOM)
+	return bytes.Map(func(r rune) rune {
+		switch {
+		case r == '\t' || r == '\n' || r == '\r':
+			return r
+		case r < 0x20, r == 0xFFFE, r == 0xFFFF:
+			return -1
+		}
+		return r
+	}, data)
+}
+
+// parseFeedLeniently retries a document the strict parser rejected with
+// strictErr. It returns the feed together with a warning describing what
+// was wrong, or strictErr if nothing could be recovered.
+func parseFeedLeniently(data []byte, strictErr error) (*RSSFeed, string, error) {
+	rssFeed, err := parseXMLFeed(sanitizeXML(data), newLenientXMLDecoder)
+	if err == nil {
+		return rssFeed, fmt.Sprintf("malformed feed, parsed leniently: %v", strictErr), nil
+	}
+
+	// Decoding stopped partway. The decoder drops the item it was in the
+	// middle of, so the items it returned are complete.
+	if rssFeed == nil || len(rssFeed.Channel.Item) == 0 {
+		return nil, "", strictErr
+	}
+	return rssFeed, fmt.Sprintf("malformed feed, kept the first %d items: %v", len(rssFeed.Channel.Item), err), nil
+}
@@ -0,0 +1,115 @@
+package app
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSanitizeXML(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want string
+	}{
+		{"byte order mark", "\xef\xbb\xbf<rss/>", "<rss/>"},
+		{"control characters", "<title>a\x00b\x08c\x1fd</title>", "<title>abcd</title>"},
+		{"whitespace kept", "<title>a\tb\r\nc</title>", "<title>a\tb\r\nc</title>"},
+		{"noncharacters", "<title>a￾b￿</title>", "<title>ab</title>"},
+		{"invalid utf-8", "<title>a\xffb</title>", "<title>a�b</title>"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := string(sanitizeXML([]byte(tt.data))); got != tt.want {
+				t.Errorf("sanitizeXML(%q) = %q, want %q", tt.data, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseFeedLeniently(t *testing.T) {
+	tests := []struct {
+		name        string
+		data        string
+		wantTitles  []string
+		wantWarning string
+	}{
+		{
+			name:        "html entity",
+			data:        `<rss><channel><title>x</title><item><title>a&nbsp;b</title></item></channel></rss>`,
+			wantTitles:  []string{"a b"},
+			wantWarning: "parsed leniently",
+		},
+		{
+			name:        "bare ampersand",
+			data:        `<rss><channel><title>x</title><item><title>Q&A</title></item></channel></rss>`,
+			wantTitles:  []string{"Q&A"},
+			wantWarning: "parsed leniently",
+		},
+		{
+			name:        "control character",
+			data:        "<rss><channel><title>x</title><item><title>a\x0bb</title></item></channel></rss>",
+			wantTitles:  []string{"ab"},
+			wantWarning: "parsed leniently",
+		},
+		{
+			name:        "unclosed html tag",
+			data:        `<rss><channel><title>x</title><item><title>a</title><description>one<br>two</description></item></channel></rss>`,
+			wantTitles:  []string{"a"},
+			wantWarning: "parsed leniently",
+		},
+		{
+			name:        "truncated inside an item",
+			data:        `<rss><channel><title>x</title><item><title>a</title></item><item><title>b</title></item><item><title>c</ti`,
+			wantTitles:  []string{"a", "b"},
+			wantWarning: "kept the first 2 items",
+		},
+		{
+			name:        "truncated between items",
+			data:        `<rss><channel><title>x</title><item><title>a</title></item><item><title>b</title></item>`,
+			wantTitles:  []string{"a", "b"},
+			wantWarning: "kept the first 2 items",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := []byte(tt.data)
+			_, strictErr := parseFeed(data, "")
+			if strictErr == nil {
+				t.Fatal("strict parse succeeded")
+			}
+
+			feed, warning, err := parseFeedLeniently(data, strictErr)
+			if err != nil {
+				t.Fatalf("parseFeedLeniently returned error: %v", err)
+			}
+			var titles []string
+			for _, item := range feed.Channel.Item {
+				titles = append(titles, item.Title)
+			}
+			if strings.Join(titles, "|") != strings.Join(tt.wantTitles, "|") {
+				t.Errorf("got titles %q, want %q", titles, tt.wantTitles)
+			}
+			if !strings.Contains(warning, tt.wantWarning) {
+				t.Errorf("warning %q does not mention %q", warning, tt.wantWarning)
+			}
+		})
+	}
+}
+
+func TestParseFeedLenientlyUnrecoverable(t *testing.T) {
+	for _, data := range []string{
+		"",
+		"not xml at all",
+		`<rss><channel><title>x</title><item><title>a</ti`,
+	} {
+		_, strictErr := parseFeed([]byte(data), "")
+		if strictErr == nil {
+			t.Fatalf("strict parse of %q succeeded", data)
+		}
+		if _, _, err := parseFeedLeniently([]byte(data), strictErr); err != strictErr {
+			t.Errorf("parseFeedLeniently(%q) error = %v, want the strict error", data, err)
+		}
+	}
+}
@@ -7,10 +7,8 @@ import (
 	"github.com/fotis-sofoulis/blog-aggregator/internal/database"
 )
 
-func MiddlewareLoggedIn(handler func(s *State, cmd Command, user database.User) error) func(*State, Command) error {
-	return func(s *State, cmd Command) error {
-		ctx := context.Background()
-
+func MiddlewareLoggedIn(handler func(ctx context.Context, s *State, cmd Command, user database.User) error) func(context.Context, *State, Command) error {
+	return func(ctx context.Context, s *State, cmd Command) error {
         currUserName := s.Cfg.CurrentUserName
         if currUserName == "" {
             return fmt.Errorf("no user is currently logged in, please login or register first")
@@ -21,6 +19,6 @@ func MiddlewareLoggedIn(handler func(s *State, cmd Command, user database.User)
             return fmt.Errorf("could not fetch current user: %w", err)
         }
 
-		return handler(s, cmd, user)
+		return handler(ctx, s, cmd, user)
 	}
 }
@@ -0,0 +1,178 @@
+package app
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/fotis-sofoulis/blog-aggregator/internal/database"
+	"github.com/google/uuid"
+)
+
+type OPML struct {
+	Body struct {
+		Outlines []OPMLOutline `xml:"outline"`
+	} `xml:"body"`
+}
+
+// OPMLOutline is either a subscription, when it has an xmlUrl, or a folder
+// of further outlines.
+type OPMLOutline struct {
+	Text     string        `xml:"text,attr"`
+	Title    string        `xml:"title,attr"`
+	XMLURL   string        `xml:"xmlUrl,attr"`
+	Outlines []OPMLOutline `xml:"outline"`
+}
+
+func (o OPMLOutline) name() string {
+	if title := strings.TrimSpace(o.Title); title != "" {
+		return title
+	}
+	return strings.TrimSpace(o.Text)
+}
+
+// opmlSubscription is a feed listed in an OPML file, with the path of the
+// folders it sits in.
+type opmlSubscription struct {
+	Name     string
+	URL      string
+	Category string
+}
+
+// subscriptions flattens outlines into their feeds. Nested folders are
+// joined with "/" to form the category.
+func subscriptions(outlines []OPMLOutline, category string) []opmlSubscription {
+	var subs []opmlSubscription
+	for _, outline := range outlines {
+		feedURL := strings.TrimSpace(outline.XMLURL)
+		if feedURL == "" {
+			folder := outline.name()
+			if category != "" && folder != "" {
+				folder = category + "/" + folder
+			} else if folder == "" {
+				folder = category
+			}
+			subs = append(subs, subscriptions(outline.Outlines, folder)...)
+			continue
+		}
+		subs = append(subs, opmlSubscription{Name: outline.name(), URL: feedURL, Category: category})
+	}
+	return subs
+}
+
+func validFeedURL(feedURL string) bool {
+	u, err := url.Parse(feedURL)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+var HandlerImportOPML = func(ctx context.Context, s *State, cmd Command, user database.User) error {
+	if len(cmd.Args) != 1 {
+		return fmt.Errorf("usage: %s <file>", cmd.Name)
+	}
+
+	data, err := os.ReadFile(cmd.Args[0])
+	if err != nil {
+		return fmt.Errorf("could not read opml file: %w", err)
+	}
+
+	var opml OPML
+	if err := newXMLDecoder(data).Decode(&opml); err != nil {
+		return fmt.Errorf("could not parse opml file: %w", err)
+	}
+
+	var added, present, invalid, repeated int
+	err = s.withTx(ctx, func(q *database.Queries) error {
+		// Feeds already handled in this import, by id so a feed listed
+		// under two of its addresses counts once too.
+		handled := map[uuid.UUID]bool{}
+		for _, sub := range subscriptions(opml.Body.Outlines, "") {
+			if !validFeedURL(sub.URL) {
+				fmt.Printf("Invalid: %q (%q is not an http or https url)\n", sub.Name, sub.URL)
+				invalid++
+				continue
+			}
+
+			now := time.Now()
+			created := false
+			feed, err := q.GetFeedByUrl(ctx, sub.URL)
+			if err == nil && handled[feed.ID] {
+				fmt.Printf("Listed more than once, skipped: %q (%s)\n", sub.Name, sub.URL)
+				repeated++
+				continue
+			}
+			if err == nil {
+				present++
+			} else if err == sql.ErrNoRows {
+				name := sub.Name
+				if name == "" {
+					name = sub.URL
+				}
+				feed, err = q.AddFeed(ctx, database.AddFeedParams{
+					ID:        uuid.New(),
+					Name:      name,
+					UserID:    user.ID,
+					Url:       sub.URL,
+					CreatedAt: now,
+					UpdatedAt: now,
+				})
+				if err != nil {
+					return fmt.Errorf("could not add feed %s: %w", sub.URL, err)
+				}
+				created = true
+				added++
+			} else {
+				return fmt.Errorf("could not get feed by url: %w", err)
+			}
+			handled[feed.ID] = true
+
+			category := sql.NullString{String: sub.Category, Valid: sub.Category != ""}
+			followed, err := q.CreateFeedFollowIfMissing(ctx, database.CreateFeedFollowIfMissingParams{
+				ID:        uuid.New(),
+				CreatedAt: now,
+				UpdatedAt: now,
+				UserID:    user.ID,
+				FeedID:    feed.ID,
+				Category:  category,
+			})
+			if err != nil {
+				return fmt.Errorf("could not follow feed %s: %w", sub.URL, err)
+			}
+
+			// An existing follow takes the category of the outline's folder,
+			// unless the outline is in no folder.
+			var recategorized int64
+			if followed == 0 && category.Valid {
+				recategorized, err = q.SetFeedFollowCategory(ctx, database.SetFeedFollowCategoryParams{
+					UserID:   user.ID,
+					FeedID:   feed.ID,
+					Category: category,
+				})
+				if err != nil {
+					return fmt.Errorf("could not set category of feed %s: %w", sub.URL, err)
+				}
+			}
+
+			switch {
+			case created:
+				fmt.Printf("Added: %s (%s)\n", feed.Name, feed.Url)
+			case followed > 0:
+				fmt.Printf("Already present, now followed: %s (%s)\n", feed.Name, feed.Url)
+			case recategorized > 0:
+				fmt.Printf("Already present and followed, category set to %q: %s (%s)\n", sub.Category, feed.Name, feed.Url)
+			default:
+				fmt.Printf("Already present and followed: %s (%s)\n", feed.Name, feed.Url)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("import failed, nothing was imported: %w", err)
+	}
+
+	fmt.Printf("\nImported %s: %d added, %d already present, %d invalid, %d listed more than once\n", cmd.Args[0], added, present, invalid, repeated)
+	return nil
+}
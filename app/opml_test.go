@@ -0,0 +1,61 @@
+package app
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSubscriptions(t *testing.T) {
+	data := []byte(`<?xml version="1.0"?>
+<opml version="2.0">
+	<head><title>Subscriptions</title></head>
+	<body>
+		<outline text="Top level" xmlUrl=" https://example.com/top.xml "/>
+		<outline text="Tech">
+			<outline text="Go blog" title="The Go Blog" xmlUrl="https://go.dev/blog/feed.atom"/>
+			<outline title="Databases">
+				<outline text="Postgres" xmlUrl="https://postgresql.org/news.rss"/>
+			</outline>
+		</outline>
+		<outline>
+			<outline text="Unnamed folder" xmlUrl="https://example.com/unnamed.xml"/>
+		</outline>
+	</body>
+</opml>`)
+
+	var opml OPML
+	if err := newXMLDecoder(data).Decode(&opml); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []opmlSubscription{
+		{Name: "Top level", URL: "https://example.com/top.xml"},
+		{Name: "The Go Blog", URL: "https://go.dev/blog/feed.atom", Category: "Tech"},
+		{Name: "Postgres", URL: "https://postgresql.org/news.rss", Category: "Tech/Databases"},
+		{Name: "Unnamed folder", URL: "https://example.com/unnamed.xml"},
+	}
+	if got := subscriptions(opml.Body.Outlines, ""); !reflect.DeepEqual(got, want) {
+		t.Errorf("subscriptions = %+v\nwant %+v", got, want)
+	}
+}
+
+func TestValidFeedURL(t *testing.T) {
+	tests := []struct {
+		url  string
+		want bool
+	}{
+		{"https://example.com/feed", true},
+		{"http://example.com", true},
+		{"feed://example.com/feed", false},
+		{"ftp://example.com/feed", false},
+		{"/relative/feed", false},
+		{"https://", false},
+		{"not a url", false},
+	}
+
+	for _, tt := range tests {
+		if got := validFeedURL(tt.url); got != tt.want {
+			t.Errorf("validFeedURL(%q) = %v, want %v", tt.url, got, tt.want)
+		}
+	}
+}
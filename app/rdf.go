@@ -0,0 +1,75 @@
+package app
+
+import (
+	"strings"
+	"time"
+)
+
+// RDFFeed is an RSS 1.0 document, where items are siblings of the channel
+// under rdf:RDF rather than children of it.
+type RDFFeed struct {
+	Channel struct {
+		Title           string `xml:"title"`
+		Link            string `xml:"link"`
+		Description     string `xml:"description"`
+		UpdatePeriod    string `xml:"http://purl.org/rss/1.0/modules/syndication/ updatePeriod"`
+		UpdateFrequency string `xml:"http://purl.org/rss/1.0/modules/syndication/ updateFrequency"`
+	} `xml:"channel"`
+	Items []RDFItem `xml:"item"`
+}
+
+type RDFItem struct {
+	About       string   `xml:"http://www.w3.org/1999/02/22-rdf-syntax-ns# about,attr"`
+	Title       string   `xml:"title"`
+	Link        string   `xml:"link"`
+	Description string   `xml:"description"`
+	Date        string   `xml:"http://purl.org/dc/elements/1.1/ date"`
+	Creator     string   `xml:"http://purl.org/dc/elements/1.1/ creator"`
+	Subjects    []string `xml:"http://purl.org/dc/elements/1.1/ subject"`
+	Content     string   `xml:"http://purl.org/rss/1.0/modules/content/ encoded"`
+}
+
+func (r *RDFFeed) toRSS() *RSSFeed {
+	var rssFeed RSSFeed
+	rssFeed.Channel.Title = r.Channel.Title
+	rssFeed.Channel.Link = r.Channel.Link
+	rssFeed.Channel.Description = r.Channel.Description
+	rssFeed.Channel.UpdatePeriod = r.Channel.UpdatePeriod
+	rssFeed.Channel.UpdateFrequency = r.Channel.UpdateFrequency
+
+	for _, item := range r.Items {
+		rssFeed.Channel.Item = append(rssFeed.Channel.Item, RSSItem{
+			GUID:        item.About,
+			Title:       item.Title,
+			Link:        strings.TrimSpace(item.Link),
+			Description: item.Description,
+			PubDate:     normalizeDCDate(item.Date),
+			Author:      item.Creator,
+			Content:     item.Content,
+			Categories:  item.Subjects,
+		})
+	}
+
+	return &rssFeed
+}
+
+// dcDateLayouts are the W3C-DTF profiles of ISO 8601 allowed in dc:date.
+var dcDateLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04Z07:00",
+	"2006-01-02",
+	"2006-01",
+	"2006",
+}
+
+// normalizeDCDate rewrites a dc:date as RFC 3339 so that the reduced
+// precision forms parse like any other feed date.
+func normalizeDCDate(date string) string {
+	date = strings.TrimSpace(date)
+	for _, layout := range dcDateLayouts {
+		if t, err := time.Parse(layout, date); err == nil {
+			return t.Format(time.RFC3339)
+		}
+	}
+	return date
+}
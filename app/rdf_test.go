@@ -0,0 +1,82 @@
+package app
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseFeedRDF(t *testing.T) {
+	data := []byte(`<?xml version="1.0"?>
+<rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#" xmlns="http://purl.org/rss/1.0/"
+	xmlns:dc="http://purl.org/dc/elements/1.1/" xmlns:sy="http://purl.org/rss/1.0/modules/syndication/"
+	xmlns:content="http://purl.org/rss/1.0/modules/content/">
+	<channel rdf:about="https://example.com/">
+		<title>Example</title>
+		<link>https://example.com/</link>
+		<description>RSS 1.0</description>
+		<sy:updatePeriod>hourly</sy:updatePeriod>
+		<sy:updateFrequency>2</sy:updateFrequency>
+	</channel>
+	<item rdf:about="https://example.com/1">
+		<title>First</title>
+		<link>
+			https://example.com/1
+		</link>
+		<description>Summary</description>
+		<dc:date>2006-01-02T15:04+01:00</dc:date>
+		<dc:creator>Jane</dc:creator>
+		<dc:subject>Go</dc:subject>
+		<dc:subject>News</dc:subject>
+		<content:encoded><![CDATA[<p>Full</p>]]></content:encoded>
+	</item>
+</rdf:RDF>`)
+
+	feed, err := parseFeed(data, "application/rdf+xml")
+	if err != nil {
+		t.Fatalf("parseFeed returned error: %v", err)
+	}
+
+	if feed.Channel.Title != "Example" || feed.Channel.Link != "https://example.com/" || feed.Channel.Description != "RSS 1.0" {
+		t.Errorf("got channel %q, %q, %q", feed.Channel.Title, feed.Channel.Link, feed.Channel.Description)
+	}
+	if feed.Channel.UpdatePeriod != "hourly" || feed.Channel.UpdateFrequency != "2" {
+		t.Errorf("got update period %q and frequency %q", feed.Channel.UpdatePeriod, feed.Channel.UpdateFrequency)
+	}
+
+	want := []RSSItem{{
+		GUID:        "https://example.com/1",
+		Title:       "First",
+		Link:        "https://example.com/1",
+		Description: "Summary",
+		PubDate:     "2006-01-02T15:04:00+01:00",
+		Author:      "Jane",
+		Content:     "<p>Full</p>",
+		Categories:  []string{"Go", "News"},
+	}}
+	if !reflect.DeepEqual(feed.Channel.Item, want) {
+		t.Errorf("items = %+v\nwant %+v", feed.Channel.Item, want)
+	}
+}
+
+func TestNormalizeDCDate(t *testing.T) {
+	tests := []struct {
+		date string
+		want string
+	}{
+		{"2006-01-02T15:04:05.123Z", "2006-01-02T15:04:05Z"},
+		{"2006-01-02T15:04:05+02:00", "2006-01-02T15:04:05+02:00"},
+		{"2006-01-02T15:04Z", "2006-01-02T15:04:00Z"},
+		{"2006-01-02", "2006-01-02T00:00:00Z"},
+		{"2006-01", "2006-01-01T00:00:00Z"},
+		{"2006", "2006-01-01T00:00:00Z"},
+		{" 2006-01-02 ", "2006-01-02T00:00:00Z"},
+		{"Mon, 02 Jan 2006 15:04:05 GMT", "Mon, 02 Jan 2006 15:04:05 GMT"},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		if got := normalizeDCDate(tt.date); got != tt.want {
+			t.Errorf("normalizeDCDate(%q) = %q, want %q", tt.date, got, tt.want)
+		}
+	}
+}
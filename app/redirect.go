@@ -0,0 +1,72 @@
+package app
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/fotis-sofoulis/blog-aggregator/internal/database"
+)
+
+// moveFeed points a permanently redirected feed at its new address and keeps
+// the old one as an alias, so lookups by either address find it. When
+// another feed already has the new address, the two are merged into that one.
+func moveFeed(ctx context.Context, s *State, feed database.Feed, newURL string) error {
+	var target database.Feed
+	err := s.withTx(ctx, func(q *database.Queries) error {
+		var err error
+		target, err = q.GetFeedByUrl(ctx, newURL)
+		if err == sql.ErrNoRows || err == nil && target.ID == feed.ID {
+			// The new address may be one of the feed's own aliases, when a
+			// move is undone.
+			if err := q.DeleteFeedAlias(ctx, newURL); err != nil {
+				return fmt.Errorf("could not delete feed alias: %w", err)
+			}
+			if err := q.SetFeedUrl(ctx, database.SetFeedUrlParams{ID: feed.ID, Url: newURL}); err != nil {
+				return fmt.Errorf("could not update feed url: %w", err)
+			}
+			target = feed
+		} else if err != nil {
+			return fmt.Errorf("could not get feed by url: %w", err)
+		} else if err := mergeFeed(ctx, q, feed, target); err != nil {
+			return err
+		}
+
+		aliasParams := database.AddFeedAliasParams{Url: feed.Url, FeedID: target.ID}
+		if err := q.AddFeedAlias(ctx, aliasParams); err != nil {
+			return fmt.Errorf("could not add feed alias: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if target.ID == feed.ID {
+		fmt.Printf("Feed %s moved permanently to %s\n", feed.Name, newURL)
+	} else {
+		fmt.Printf("Feed %s moved permanently to %s and was merged into %s\n", feed.Name, newURL, target.Name)
+	}
+	return nil
+}
+
+// mergeFeed moves feed's follows, posts, fetch history and aliases to target
+// and deletes feed. Follows and posts that target already has are dropped.
+func mergeFeed(ctx context.Context, q *database.Queries, feed, target database.Feed) error {
+	if err := q.MoveFeedFollows(ctx, database.MoveFeedFollowsParams{ToFeedID: target.ID, FromFeedID: feed.ID}); err != nil {
+		return fmt.Errorf("could not move feed follows: %w", err)
+	}
+	if err := q.MoveFeedPosts(ctx, database.MoveFeedPostsParams{ToFeedID: target.ID, FromFeedID: feed.ID}); err != nil {
+		return fmt.Errorf("could not move posts: %w", err)
+	}
+	if err := q.MoveFeedFetches(ctx, database.MoveFeedFetchesParams{ToFeedID: target.ID, FromFeedID: feed.ID}); err != nil {
+		return fmt.Errorf("could not move fetch history: %w", err)
+	}
+	if err := q.MoveFeedAliases(ctx, database.MoveFeedAliasesParams{ToFeedID: target.ID, FromFeedID: feed.ID}); err != nil {
+		return fmt.Errorf("could not move feed aliases: %w", err)
+	}
+	if err := q.DeleteFeed(ctx, feed.ID); err != nil {
+		return fmt.Errorf("could not delete merged feed: %w", err)
+	}
+	return nil
+}
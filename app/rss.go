@@ -1,32 +1,168 @@
 package app
 
 import (
+	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"encoding/xml"
 	"errors"
 	"fmt"
-	"io"
 	"html"
+	"io"
+	"mime"
 	"net/http"
+	"net/url"
+	"strings"
 )
 
 type RSSFeed struct {
 	Channel struct {
-		Title       string    `xml:"title"`
-		Link        string    `xml:"link"`
-		Description string    `xml:"description"`
-		Item        []RSSItem `xml:"item"`
+		Title           string    `xml:"title"`
+		Link            string    `xml:"-"`
+		Links           []string  `xml:"link"`
+		Description     string    `xml:"description"`
+		TTL             string    `xml:"ttl"`
+		SkipHours       []string  `xml:"skipHours>hour"`
+		SkipDays        []string  `xml:"skipDays>day"`
+		UpdatePeriod    string    `xml:"http://purl.org/rss/1.0/modules/syndication/ updatePeriod"`
+		UpdateFrequency string    `xml:"http://purl.org/rss/1.0/modules/syndication/ updateFrequency"`
+		Item            []RSSItem `xml:"item"`
 	} `xml:"channel"`
 }
 
 type RSSItem struct {
-	Title       string `xml:"title"`
-	Link        string `xml:"link"`
-	Description string `xml:"description"`
-	PubDate     string `xml:"pubDate"`
+	GUID        string         `xml:"guid"`
+	Title       string         `xml:"title"`
+	Link        string         `xml:"-"`
+	Links       []string       `xml:"link"`
+	Description string         `xml:"description"`
+	PubDate     string         `xml:"pubDate"`
+	Author      string         `xml:"author"`
+	Creator     string         `xml:"http://purl.org/dc/elements/1.1/ creator"`
+	Content     string         `xml:"http://purl.org/rss/1.0/modules/content/ encoded"`
+	Categories  []string       `xml:"category"`
+	Enclosures  []RSSEnclosure `xml:"enclosure"`
+	Media       []MediaContent `xml:"http://search.yahoo.com/mrss/ content"`
+	MediaGroup  []MediaContent `xml:"http://search.yahoo.com/mrss/ group>content"`
+}
+
+type RSSEnclosure struct {
+	URL    string `xml:"url,attr"`
+	Type   string `xml:"type,attr"`
+	Length string `xml:"length,attr"`
+}
+
+// MediaContent is a Media RSS <media:content>, which podcasts and video
+// feeds use alongside or instead of <enclosure>.
+type MediaContent struct {
+	URL      string `xml:"url,attr"`
+	Type     string `xml:"type,attr"`
+	FileSize string `xml:"fileSize,attr"`
+}
+
+// firstLink returns the first non-empty of links. An rss document's <link>
+// also matches the empty <atom:link rel="self"/> that many feeds include, so
+// links are decoded as a list and the text one picked out afterwards.
+func firstLink(links []string) string {
+	for _, link := range links {
+		if strings.TrimSpace(link) != "" {
+			return link
+		}
+	}
+	return ""
+}
+
+// setLinks fills in the channel's and items' Link from their decoded links.
+func (f *RSSFeed) setLinks() {
+	f.Channel.Link = firstLink(f.Channel.Links)
+	for i, item := range f.Channel.Item {
+		f.Channel.Item[i].Link = firstLink(item.Links)
+	}
+}
+
+// resolveReference resolves a relative ref against base, which may be an
+// atom xml:base or the url the feed was fetched from. Absolute refs, and refs
+// that cannot be resolved, are returned unchanged.
+func resolveReference(base, ref string) string {
+	refURL, err := url.Parse(strings.TrimSpace(ref))
+	if err != nil || refURL.IsAbs() || strings.TrimSpace(ref) == "" {
+		return ref
+	}
+	baseURL, err := url.Parse(strings.TrimSpace(base))
+	if err != nil || strings.TrimSpace(base) == "" {
+		return ref
+	}
+	return baseURL.ResolveReference(refURL).String()
+}
+
+// resolveLinks makes the relative links left in the feed absolute against
+// the url it was fetched from.
+func (f *RSSFeed) resolveLinks(feedURL string) {
+	f.Channel.Link = resolveReference(feedURL, f.Channel.Link)
+	for i, item := range f.Channel.Item {
+		f.Channel.Item[i].Link = resolveReference(feedURL, item.Link)
+		for j, enclosure := range item.Enclosures {
+			f.Channel.Item[i].Enclosures[j].URL = resolveReference(feedURL, enclosure.URL)
+		}
+	}
 }
 
-func FetchFeed(ctx context.Context, feedURL string) (*RSSFeed, error) {
+// Identity returns what tells this item apart from the feed's other items:
+// its guid, else its link, else a hash of its content for items that have
+// neither.
+func (i RSSItem) Identity() string {
+	if guid := strings.TrimSpace(i.GUID); guid != "" {
+		return guid
+	}
+	if link := strings.TrimSpace(i.Link); link != "" {
+		return link
+	}
+	return "sha256:" + hashFields(i.Title, i.Description)
+}
+
+// ContentHash changes whenever a field we store for the item changes.
+func (i RSSItem) ContentHash() string {
+	fields := []string{i.Title, i.Link, i.Description, i.PubDate, i.Author, i.Content}
+	fields = append(fields, i.Categories...)
+	for _, enclosure := range i.Enclosures {
+		fields = append(fields, enclosure.URL, enclosure.Type, enclosure.Length)
+	}
+	return hashFields(fields...)
+}
+
+func hashFields(fields ...string) string {
+	sum := sha256.Sum256([]byte(strings.Join(fields, "\x00")))
+	return hex.EncodeToString(sum[:])
+}
+
+// CacheValidators are the ETag and Last-Modified headers from a previous
+// fetch, sent back so the server can answer 304 Not Modified.
+type CacheValidators struct {
+	ETag         string
+	LastModified string
+}
+
+// FetchResult describes a feed response. FetchFeed returns it whenever the
+// server answered, alongside any error from reading or parsing the body.
+type FetchResult struct {
+	Feed        *RSSFeed
+	NotModified bool
+	Validators  CacheValidators
+	StatusCode  int
+	Bytes       int64
+	// Warning describes what was wrong with a malformed feed that was
+	// still parsed.
+	Warning string
+	// PermanentURL is where the feed was permanently redirected, if it was.
+	PermanentURL string
+}
+
+// FetchFeed fetches and parses the feed at feedURL. Responses that cannot be
+// a feed are rejected with a *StatusError, *ContentTypeError or
+// *BodyTooLargeError.
+func (f *Fetcher) FetchFeed(ctx context.Context, feedURL string, validators CacheValidators) (*FetchResult, error) {
 	if feedURL == "" {
 		return nil, errors.New("no feed url given")
 	}
@@ -36,22 +172,64 @@ func FetchFeed(ctx context.Context, feedURL string) (*RSSFeed, error) {
 		return nil, fmt.Errorf("error in creating request: %w", err)
 	}
 	req.Header.Set("User-Agent", "gator")
+	if validators.ETag != "" {
+		req.Header.Set("If-None-Match", validators.ETag)
+	}
+	if validators.LastModified != "" {
+		req.Header.Set("If-Modified-Since", validators.LastModified)
+	}
 
-	client := http.Client{}
-	res, err := client.Do(req)
+	res, err := f.client.Do(req)
 	if err != nil {
 		return nil, fmt.Errorf("error in making the request: %w", err)
 	}
 	defer res.Body.Close()
 
-	data, err := io.ReadAll(res.Body)
+	result := &FetchResult{StatusCode: res.StatusCode, PermanentURL: permanentRedirect(res)}
+	if res.StatusCode == http.StatusNotModified {
+		result.NotModified = true
+		result.Validators = validators
+		return result, nil
+	}
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return result, &StatusError{StatusCode: res.StatusCode, Status: res.Status}
+	}
+
+	contentType := res.Header.Get("Content-Type")
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	if isNonFeedType(mediaType) {
+		return result, &ContentTypeError{ContentType: contentType}
+	}
+	if res.ContentLength > f.maxBodyBytes {
+		return result, &BodyTooLargeError{Limit: f.maxBodyBytes}
+	}
+
+	data, err := io.ReadAll(io.LimitReader(res.Body, f.maxBodyBytes+1))
+	result.Bytes = int64(len(data))
+	if err != nil {
+		return result, fmt.Errorf("error reading response body: %w", err)
+	}
+	if result.Bytes > f.maxBodyBytes {
+		return result, &BodyTooLargeError{Limit: f.maxBodyBytes}
+	}
+
+	data, err = toUTF8(data, contentType)
 	if err != nil {
-		return nil, fmt.Errorf("error reading response body: %w", err)
+		return result, fmt.Errorf("error decoding response body: %w", err)
+	}
+
+	// Some servers label every response text/html, so an html type only
+	// rules a response out once the body shows it is a web page.
+	if isHTMLType(mediaType) && !looksLikeFeed(data) {
+		return result, &ContentTypeError{ContentType: contentType}
 	}
 
-	var rssFeed RSSFeed
-	if err := xml.Unmarshal(data, &rssFeed); err != nil {
-		return nil, fmt.Errorf("error in unmarshalling xml: %w", err)
+	rssFeed, err := parseFeed(data, contentType)
+	if err != nil && !isJSONFeed(data, contentType) {
+		rssFeed, result.Warning, err = parseFeedLeniently(data, err)
+	}
+	if err != nil {
+		return result, err
 	}
 
 	rssFeed.Channel.Title = html.UnescapeString(rssFeed.Channel.Title)
@@ -59,7 +237,136 @@ func FetchFeed(ctx context.Context, feedURL string) (*RSSFeed, error) {
 	for i, item := range rssFeed.Channel.Item {
 		rssFeed.Channel.Item[i].Title = html.UnescapeString(item.Title)
 		rssFeed.Channel.Item[i].Description = html.UnescapeString(item.Description)
+		if item.Author == "" {
+			rssFeed.Channel.Item[i].Author = item.Creator
+		}
+		rssFeed.Channel.Item[i].Author = strings.TrimSpace(rssFeed.Channel.Item[i].Author)
+		rssFeed.Channel.Item[i].Categories = cleanCategories(item.Categories)
+		rssFeed.Channel.Item[i].Enclosures = mergeEnclosures(item)
 	}
+	rssFeed.resolveLinks(res.Request.URL.String())
 
-	return &rssFeed, nil
+	result.Feed = rssFeed
+	result.Validators = CacheValidators{
+		ETag:         res.Header.Get("ETag"),
+		LastModified: res.Header.Get("Last-Modified"),
+	}
+	return result, nil
+}
+
+// cleanCategories trims category names and drops empty and repeated ones.
+func cleanCategories(categories []string) []string {
+	seen := map[string]bool{}
+	var cleaned []string
+	for _, category := range categories {
+		category = strings.TrimSpace(html.UnescapeString(category))
+		if category == "" || seen[category] {
+			continue
+		}
+		seen[category] = true
+		cleaned = append(cleaned, category)
+	}
+	return cleaned
+}
+
+// mergeEnclosures folds an item's Media RSS content into its enclosures,
+// keeping the first entry seen for each url.
+func mergeEnclosures(item RSSItem) []RSSEnclosure {
+	enclosures := item.Enclosures
+	for _, media := range append(item.Media, item.MediaGroup...) {
+		enclosures = append(enclosures, RSSEnclosure{URL: media.URL, Type: media.Type, Length: media.FileSize})
+	}
+
+	seen := map[string]bool{}
+	var merged []RSSEnclosure
+	for _, enclosure := range enclosures {
+		enclosure.URL = strings.TrimSpace(enclosure.URL)
+		if enclosure.URL == "" || seen[enclosure.URL] {
+			continue
+		}
+		seen[enclosure.URL] = true
+		enclosure.Type = strings.TrimSpace(enclosure.Type)
+		enclosure.Length = strings.TrimSpace(enclosure.Length)
+		merged = append(merged, enclosure)
+	}
+	return merged
+}
+
+// parseFeed detects the feed format from the content type or the document's
+// root element and normalizes it into an RSSFeed.
+func parseFeed(data []byte, contentType string) (*RSSFeed, error) {
+	if isJSONFeed(data, contentType) {
+		var jsonFeed JSONFeed
+		if err := json.Unmarshal(data, &jsonFeed); err != nil {
+			return nil, fmt.Errorf("error in unmarshalling json feed: %w", err)
+		}
+		// Any JSON object unmarshals, so an API error or some other document
+		// served as JSON is told apart by the version every feed declares.
+		if !strings.HasPrefix(jsonFeed.Version, jsonFeedVersionPrefix) {
+			return nil, fmt.Errorf("not a json feed: version %q", jsonFeed.Version)
+		}
+		return jsonFeed.toRSS(), nil
+	}
+
+	rssFeed, err := parseXMLFeed(data, newXMLDecoder)
+	if err != nil {
+		return nil, err
+	}
+	return rssFeed, nil
+}
+
+// parseXMLFeed decodes an rss, atom or rdf document using decoders from
+// newDecoder. When decoding fails partway through it returns what was
+// decoded so far along with the error.
+func parseXMLFeed(data []byte, newDecoder func([]byte) *xml.Decoder) (*RSSFeed, error) {
+	root, err := rootElement(newDecoder(data))
+	if err != nil {
+		return nil, fmt.Errorf("error in reading xml: %w", err)
+	}
+
+	switch root {
+	case "rss":
+		var rssFeed RSSFeed
+		err := newDecoder(data).Decode(&rssFeed)
+		rssFeed.setLinks()
+		if err != nil {
+			return &rssFeed, fmt.Errorf("error in unmarshalling rss: %w", err)
+		}
+		return &rssFeed, nil
+	case "feed":
+		var atomFeed AtomFeed
+		if err := newDecoder(data).Decode(&atomFeed); err != nil {
+			return atomFeed.toRSS(), fmt.Errorf("error in unmarshalling atom: %w", err)
+		}
+		return atomFeed.toRSS(), nil
+	case "RDF":
+		var rdfFeed RDFFeed
+		if err := newDecoder(data).Decode(&rdfFeed); err != nil {
+			return rdfFeed.toRSS(), fmt.Errorf("error in unmarshalling rdf: %w", err)
+		}
+		return rdfFeed.toRSS(), nil
+	default:
+		return nil, fmt.Errorf("unsupported feed format: <%s>", root)
+	}
+}
+
+func isJSONFeed(data []byte, contentType string) bool {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch mediaType {
+	case "application/feed+json", "application/json":
+		return true
+	}
+	return bytes.HasPrefix(bytes.TrimSpace(data), []byte("{"))
+}
+
+func rootElement(decoder *xml.Decoder) (string, error) {
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return "", err
+		}
+		if start, ok := token.(xml.StartElement); ok {
+			return start.Name.Local, nil
+		}
+	}
 }
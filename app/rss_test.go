@@ -0,0 +1,158 @@
+package app
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseFeedRSS(t *testing.T) {
+	data := []byte(`<?xml version="1.0" encoding="UTF-8"?>
+<rss version="2.0" xmlns:atom="http://www.w3.org/2005/Atom" xmlns:dc="http://purl.org/dc/elements/1.1/"
+	xmlns:content="http://purl.org/rss/1.0/modules/content/" xmlns:media="http://search.yahoo.com/mrss/">
+<channel>
+	<title>Example</title>
+	<atom:link href="https://example.com/feed.xml" rel="self" type="application/rss+xml"/>
+	<link>https://example.com/</link>
+	<description>An example feed</description>
+	<item>
+		<title>First</title>
+		<link>https://example.com/first</link>
+		<guid isPermaLink="false">tag:example.com,2024:1</guid>
+		<pubDate>Mon, 02 Jan 2006 15:04:05 GMT</pubDate>
+		<dc:creator>Jane Doe</dc:creator>
+		<content:encoded><![CDATA[<p>Full text</p>]]></content:encoded>
+		<category>Go</category>
+		<category>News</category>
+		<enclosure url="https://example.com/first.mp3" type="audio/mpeg" length="1234"/>
+		<media:content url="https://example.com/first.mp4" type="video/mp4" fileSize="5678"/>
+	</item>
+</channel>
+</rss>`)
+
+	feed, err := parseFeed(data, "application/rss+xml")
+	if err != nil {
+		t.Fatalf("parseFeed returned error: %v", err)
+	}
+
+	if feed.Channel.Title != "Example" || feed.Channel.Description != "An example feed" {
+		t.Errorf("got channel %q, %q", feed.Channel.Title, feed.Channel.Description)
+	}
+	// The empty <atom:link> before <link> must not hide the site link.
+	if feed.Channel.Link != "https://example.com/" {
+		t.Errorf("channel link = %q, want https://example.com/", feed.Channel.Link)
+	}
+
+	if len(feed.Channel.Item) != 1 {
+		t.Fatalf("got %d items, want 1", len(feed.Channel.Item))
+	}
+	item := feed.Channel.Item[0]
+	if item.Identity() != "tag:example.com,2024:1" {
+		t.Errorf("identity = %q", item.Identity())
+	}
+	if item.Link != "https://example.com/first" || item.Creator != "Jane Doe" || item.Content != "<p>Full text</p>" {
+		t.Errorf("got link %q, creator %q, content %q", item.Link, item.Creator, item.Content)
+	}
+	if want := []string{"Go", "News"}; !reflect.DeepEqual(item.Categories, want) {
+		t.Errorf("categories = %q, want %q", item.Categories, want)
+	}
+	wantEnclosures := []RSSEnclosure{
+		{URL: "https://example.com/first.mp3", Type: "audio/mpeg", Length: "1234"},
+		{URL: "https://example.com/first.mp4", Type: "video/mp4", Length: "5678"},
+	}
+	if got := mergeEnclosures(item); !reflect.DeepEqual(got, wantEnclosures) {
+		t.Errorf("enclosures = %+v, want %+v", got, wantEnclosures)
+	}
+}
+
+func TestParseFeedFormats(t *testing.T) {
+	tests := []struct {
+		name        string
+		data        string
+		contentType string
+		wantTitle   string
+		wantItems   int
+	}{
+		{"rss", `<rss><channel><title>t</title><item><title>a</title></item></channel></rss>`, "", "t", 1},
+		{"rss with byte order mark", "\xef\xbb\xbf<rss><channel><title>t</title></channel></rss>", "", "t", 0},
+		{"rss with trailing garbage", `<rss><channel><title>t</title></channel></rss><!-- 12ms --> &`, "", "t", 0},
+		{"atom", `<feed xmlns="http://www.w3.org/2005/Atom"><title>t</title><entry><title>a</title></entry></feed>`, "", "t", 1},
+		{"rdf", `<rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#" xmlns="http://purl.org/rss/1.0/"><channel><title>t</title></channel><item><title>a</title></item></rdf:RDF>`, "", "t", 1},
+		{"json by content type", ` {"version": "https://jsonfeed.org/version/1", "title": "t", "items": [{"id": "1"}]}`, "application/feed+json", "t", 1},
+		{"json by body", `{"version": "https://jsonfeed.org/version/1.1", "title": "t", "items": []}`, "text/plain", "t", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			feed, err := parseFeed([]byte(tt.data), tt.contentType)
+			if err != nil {
+				t.Fatalf("parseFeed returned error: %v", err)
+			}
+			if feed.Channel.Title != tt.wantTitle || len(feed.Channel.Item) != tt.wantItems {
+				t.Errorf("got title %q and %d items, want %q and %d", feed.Channel.Title, len(feed.Channel.Item), tt.wantTitle, tt.wantItems)
+			}
+		})
+	}
+}
+
+func TestParseFeedUnsupported(t *testing.T) {
+	for _, data := range []string{
+		"",
+		"<html><body>hi</body></html>",
+		`{"title": `,
+		`{}`,
+		`{"error": "rate limited"}`,
+		`{"version": "1.1", "items": []}`,
+	} {
+		if _, err := parseFeed([]byte(data), ""); err == nil {
+			t.Errorf("parseFeed(%q) succeeded, want error", data)
+		}
+	}
+}
+
+func TestRSSItemIdentity(t *testing.T) {
+	tests := []struct {
+		name string
+		item RSSItem
+		want string
+	}{
+		{"guid", RSSItem{GUID: " g ", Link: "https://example.com/a"}, "g"},
+		{"link", RSSItem{Link: " https://example.com/a "}, "https://example.com/a"},
+		{"hash", RSSItem{Title: "t", Description: "d"}, "sha256:" + hashFields("t", "d")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.item.Identity(); got != tt.want {
+				t.Errorf("Identity() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCleanCategories(t *testing.T) {
+	got := cleanCategories([]string{" Go ", "", "News", "Go", "R&amp;D", "  "})
+	if want := []string{"Go", "News", "R&D"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("cleanCategories = %q, want %q", got, want)
+	}
+}
+
+func TestResolveReference(t *testing.T) {
+	tests := []struct {
+		base string
+		ref  string
+		want string
+	}{
+		{"https://example.com/blog/feed.xml", "/posts/a", "https://example.com/posts/a"},
+		{"https://example.com/blog/feed.xml", "posts/a", "https://example.com/blog/posts/a"},
+		{"https://example.com/blog/feed.xml", "//cdn.example.com/a.mp3", "https://cdn.example.com/a.mp3"},
+		{"https://example.com/blog/feed.xml", "https://example.org/a b", "https://example.org/a b"},
+		{"https://example.com/blog/feed.xml", "", ""},
+		{"", "/posts/a", "/posts/a"},
+	}
+
+	for _, tt := range tests {
+		if got := resolveReference(tt.base, tt.ref); got != tt.want {
+			t.Errorf("resolveReference(%q, %q) = %q, want %q", tt.base, tt.ref, got, tt.want)
+		}
+	}
+}
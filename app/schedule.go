@@ -0,0 +1,176 @@
+package app
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fotis-sofoulis/blog-aggregator/internal/database"
+)
+
+const (
+	// maxDeclaredInterval caps what a feed may ask for through <ttl> or the
+	// syndication module, so a typo cannot park it for months.
+	maxDeclaredInterval = 7 * 24 * time.Hour
+
+	// Feeds are polled at half their average gap between posts, kept
+	// within these bounds.
+	minLearnedInterval = 15 * time.Minute
+	maxLearnedInterval = 24 * time.Hour
+)
+
+// updateHints are a feed's own statements about how often it changes.
+type updateHints struct {
+	MinInterval time.Duration
+	SkipHours   map[int]bool
+	SkipDays    map[time.Weekday]bool
+}
+
+var syndicationPeriods = map[string]time.Duration{
+	"hourly":  time.Hour,
+	"daily":   24 * time.Hour,
+	"weekly":  7 * 24 * time.Hour,
+	"monthly": 30 * 24 * time.Hour,
+	"yearly":  365 * 24 * time.Hour,
+}
+
+// updateHints reads <ttl>, <skipHours>, <skipDays> and the syndication
+// module's sy:updatePeriod and sy:updateFrequency.
+func (f *RSSFeed) updateHints() updateHints {
+	channel := f.Channel
+	hints := updateHints{
+		SkipHours: map[int]bool{},
+		SkipDays:  map[time.Weekday]bool{},
+	}
+
+	if ttl, err := strconv.Atoi(strings.TrimSpace(channel.TTL)); err == nil && ttl > 0 {
+		hints.MinInterval = time.Duration(ttl) * time.Minute
+	}
+
+	if channel.UpdatePeriod != "" || channel.UpdateFrequency != "" {
+		period, ok := syndicationPeriods[strings.ToLower(strings.TrimSpace(channel.UpdatePeriod))]
+		if !ok {
+			period = syndicationPeriods["daily"]
+		}
+		frequency, err := strconv.Atoi(strings.TrimSpace(channel.UpdateFrequency))
+		if err != nil || frequency < 1 {
+			frequency = 1
+		}
+		hints.MinInterval = max(hints.MinInterval, period/time.Duration(frequency))
+	}
+	hints.MinInterval = min(hints.MinInterval, maxDeclaredInterval)
+
+	for _, hour := range channel.SkipHours {
+		if h, err := strconv.Atoi(strings.TrimSpace(hour)); err == nil && h >= 0 && h < 24 {
+			hints.SkipHours[h] = true
+		}
+	}
+	for _, day := range channel.SkipDays {
+		for weekday := time.Sunday; weekday <= time.Saturday; weekday++ {
+			if strings.EqualFold(strings.TrimSpace(day), weekday.String()) {
+				hints.SkipDays[weekday] = true
+			}
+		}
+	}
+
+	return hints
+}
+
+// nextFetchTime picks when a feed is next due: no sooner than it declared,
+// nor sooner than its posting rate warrants, and outside the hours and days
+// (in GMT) it asked to be skipped.
+func nextFetchTime(now time.Time, hints updateHints, postingInterval time.Duration) time.Time {
+	interval := hints.MinInterval
+	if postingInterval > 0 {
+		learned := min(max(postingInterval/2, minLearnedInterval), maxLearnedInterval)
+		interval = max(interval, learned)
+	}
+
+	next := now.Add(interval).UTC()
+	// A week of hours covers every skipHours and skipDays combination that
+	// leaves at least one hour open.
+	for range 7 * 24 {
+		if !hints.SkipHours[next.Hour()] && !hints.SkipDays[next.Weekday()] {
+			break
+		}
+		next = next.Truncate(time.Hour).Add(time.Hour)
+	}
+
+	return next.In(now.Location())
+}
+
+// scheduleFeed records a successful fetch and sets when the feed is due
+// again. A poll interval set by a user takes precedence over anything the
+// feed declares or we learn.
+func scheduleFeed(ctx context.Context, s *State, feed database.Feed, hints updateHints) error {
+	now := time.Now()
+	next := now.Add(time.Duration(feed.PollInterval.Int32) * time.Second)
+	if !feed.PollInterval.Valid {
+		averageSeconds, err := s.Db.GetFeedPostingInterval(ctx, feed.ID)
+		if err != nil {
+			return fmt.Errorf("could not get posting interval: %w", err)
+		}
+		postingInterval := time.Duration(averageSeconds * float64(time.Second))
+		next = nextFetchTime(now, hints, postingInterval)
+	}
+
+	args := database.RecordFeedSuccessParams{
+		ID:          feed.ID,
+		NextFetchAt: sql.NullTime{Time: next, Valid: true},
+		MinInterval: sql.NullInt32{Int32: int32(hints.MinInterval.Seconds()), Valid: hints.MinInterval > 0},
+	}
+	var skipHours, skipDays []string
+	for hour := range 24 {
+		if hints.SkipHours[hour] {
+			skipHours = append(skipHours, strconv.Itoa(hour))
+		}
+	}
+	for weekday := time.Sunday; weekday <= time.Saturday; weekday++ {
+		if hints.SkipDays[weekday] {
+			skipDays = append(skipDays, weekday.String())
+		}
+	}
+	args.SkipHours = sql.NullString{String: strings.Join(skipHours, ","), Valid: len(skipHours) > 0}
+	args.SkipDays = sql.NullString{String: strings.Join(skipDays, ","), Valid: len(skipDays) > 0}
+	return s.Db.RecordFeedSuccess(ctx, args)
+}
+
+// storedHints returns the hints saved from the feed's last full fetch, for
+// when the server answers 304 Not Modified and there is no document to read
+// them from.
+func storedHints(feed database.Feed) updateHints {
+	var channel RSSFeed
+	channel.Channel.SkipHours = strings.Split(feed.SkipHours.String, ",")
+	channel.Channel.SkipDays = strings.Split(feed.SkipDays.String, ",")
+	hints := channel.updateHints()
+	hints.MinInterval = time.Duration(feed.MinInterval.Int32) * time.Second
+	return hints
+}
+
+// maxPollInterval bounds poll intervals set by users, well within the
+// seconds an int32 column holds.
+const maxPollInterval = 365 * 24 * time.Hour
+
+// parsePollInterval parses a user supplied poll interval. "default" clears
+// the override.
+func parsePollInterval(value string) (sql.NullInt32, error) {
+	if value == "default" {
+		return sql.NullInt32{}, nil
+	}
+
+	interval, err := time.ParseDuration(value)
+	if err != nil {
+		return sql.NullInt32{}, fmt.Errorf("error parsing interval: %w", err)
+	}
+	if interval < time.Second {
+		return sql.NullInt32{}, fmt.Errorf("interval must be at least 1s, got %s", interval)
+	}
+	if interval > maxPollInterval {
+		return sql.NullInt32{}, fmt.Errorf("interval must be at most %s, got %s", maxPollInterval, interval)
+	}
+
+	return sql.NullInt32{Int32: int32(interval.Seconds()), Valid: true}, nil
+}
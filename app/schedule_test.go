@@ -0,0 +1,198 @@
+package app
+
+import (
+	"database/sql"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/fotis-sofoulis/blog-aggregator/internal/database"
+)
+
+func TestUpdateHints(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want updateHints
+	}{
+		{
+			name: "none",
+			data: `<rss><channel></channel></rss>`,
+			want: updateHints{},
+		},
+		{
+			name: "ttl",
+			data: `<rss><channel><ttl> 60 </ttl></channel></rss>`,
+			want: updateHints{MinInterval: time.Hour},
+		},
+		{
+			name: "syndication module",
+			data: `<rss xmlns:sy="http://purl.org/rss/1.0/modules/syndication/"><channel>
+				<sy:updatePeriod>daily</sy:updatePeriod><sy:updateFrequency>4</sy:updateFrequency></channel></rss>`,
+			want: updateHints{MinInterval: 6 * time.Hour},
+		},
+		{
+			name: "longest of ttl and syndication module",
+			data: `<rss xmlns:sy="http://purl.org/rss/1.0/modules/syndication/"><channel>
+				<ttl>180</ttl><sy:updatePeriod>hourly</sy:updatePeriod></channel></rss>`,
+			want: updateHints{MinInterval: 3 * time.Hour},
+		},
+		{
+			name: "capped",
+			data: `<rss><channel><ttl>525600</ttl></channel></rss>`,
+			want: updateHints{MinInterval: maxDeclaredInterval},
+		},
+		{
+			name: "skip hours and days",
+			data: `<rss><channel>
+				<skipHours><hour>0</hour><hour> 23 </hour><hour>24</hour><hour>x</hour></skipHours>
+				<skipDays><day>saturday</day><day>Sunday</day><day>Someday</day></skipDays></channel></rss>`,
+			want: updateHints{
+				SkipHours: map[int]bool{0: true, 23: true},
+				SkipDays:  map[time.Weekday]bool{time.Saturday: true, time.Sunday: true},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			feed, err := parseFeed([]byte(tt.data), "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.want.SkipHours == nil {
+				tt.want.SkipHours = map[int]bool{}
+			}
+			if tt.want.SkipDays == nil {
+				tt.want.SkipDays = map[time.Weekday]bool{}
+			}
+			if got := feed.updateHints(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("updateHints = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNextFetchTime(t *testing.T) {
+	// A Friday.
+	now := time.Date(2024, 3, 1, 10, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		name            string
+		hints           updateHints
+		postingInterval time.Duration
+		want            time.Time
+	}{
+		{
+			name: "no hints",
+			want: now,
+		},
+		{
+			name:  "declared interval",
+			hints: updateHints{MinInterval: time.Hour},
+			want:  now.Add(time.Hour),
+		},
+		{
+			name:            "half the posting interval",
+			postingInterval: 4 * time.Hour,
+			want:            now.Add(2 * time.Hour),
+		},
+		{
+			name:            "posting interval at least the minimum",
+			postingInterval: time.Minute,
+			want:            now.Add(minLearnedInterval),
+		},
+		{
+			name:            "posting interval at most the maximum",
+			postingInterval: 30 * 24 * time.Hour,
+			want:            now.Add(maxLearnedInterval),
+		},
+		{
+			name:            "declared interval beats posting interval",
+			hints:           updateHints{MinInterval: 6 * time.Hour},
+			postingInterval: 4 * time.Hour,
+			want:            now.Add(6 * time.Hour),
+		},
+		{
+			name:  "skipped hours",
+			hints: updateHints{MinInterval: time.Hour, SkipHours: map[int]bool{11: true, 12: true}},
+			want:  time.Date(2024, 3, 1, 13, 0, 0, 0, time.UTC),
+		},
+		{
+			name:  "skipped days",
+			hints: updateHints{MinInterval: 14 * time.Hour, SkipDays: map[time.Weekday]bool{time.Saturday: true, time.Sunday: true}},
+			want:  time.Date(2024, 3, 4, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:  "every hour skipped",
+			hints: updateHints{SkipDays: map[time.Weekday]bool{0: true, 1: true, 2: true, 3: true, 4: true, 5: true, 6: true}},
+			want:  now.Truncate(time.Hour).Add(7 * 24 * time.Hour),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := nextFetchTime(now, tt.hints, tt.postingInterval); !got.Equal(tt.want) {
+				t.Errorf("nextFetchTime = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNextFetchTimeKeepsLocation(t *testing.T) {
+	// skipHours are in GMT whatever the local zone.
+	zone := time.FixedZone("UTC+2", 2*60*60)
+	now := time.Date(2024, 3, 1, 10, 0, 0, 0, zone)
+	hints := updateHints{MinInterval: time.Hour, SkipHours: map[int]bool{9: true}}
+
+	got := nextFetchTime(now, hints, 0)
+	if want := time.Date(2024, 3, 1, 12, 0, 0, 0, zone); !got.Equal(want) || got.Location() != zone {
+		t.Errorf("nextFetchTime = %v, want %v", got, want)
+	}
+}
+
+func TestStoredHints(t *testing.T) {
+	feed := database.Feed{
+		MinInterval: sql.NullInt32{Int32: 3600, Valid: true},
+		SkipHours:   sql.NullString{String: "0,1,23", Valid: true},
+		SkipDays:    sql.NullString{String: "Saturday,Sunday", Valid: true},
+	}
+	want := updateHints{
+		MinInterval: time.Hour,
+		SkipHours:   map[int]bool{0: true, 1: true, 23: true},
+		SkipDays:    map[time.Weekday]bool{time.Saturday: true, time.Sunday: true},
+	}
+	if got := storedHints(feed); !reflect.DeepEqual(got, want) {
+		t.Errorf("storedHints = %+v, want %+v", got, want)
+	}
+
+	empty := updateHints{SkipHours: map[int]bool{}, SkipDays: map[time.Weekday]bool{}}
+	if got := storedHints(database.Feed{}); !reflect.DeepEqual(got, empty) {
+		t.Errorf("storedHints of a feed without hints = %+v, want none", got)
+	}
+}
+
+func TestParsePollInterval(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    sql.NullInt32
+		wantErr bool
+	}{
+		{value: "default", want: sql.NullInt32{}},
+		{value: "90s", want: sql.NullInt32{Int32: 90, Valid: true}},
+		{value: "2h", want: sql.NullInt32{Int32: 7200, Valid: true}},
+		{value: "8760h", want: sql.NullInt32{Int32: 31536000, Valid: true}},
+		{value: "500ms", wantErr: true},
+		{value: "8761h", wantErr: true},
+		{value: "600000h", wantErr: true},
+		{value: "-1h", wantErr: true},
+		{value: "hourly", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := parsePollInterval(tt.value)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("parsePollInterval(%q) = %v, %v; want %v, error %v", tt.value, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
@@ -1,11 +1,37 @@
 package app
 
 import (
+	"context"
+	"database/sql"
+	"fmt"
+
 	"github.com/fotis-sofoulis/blog-aggregator/internal/config"
 	"github.com/fotis-sofoulis/blog-aggregator/internal/database"
 )
 
 type State struct {
-	Cfg *config.Config
-	Db  *database.Queries
+	Cfg     *config.Config
+	Conn    *sql.DB
+	Db      *database.Queries
+	Fetcher *Fetcher
+}
+
+// withTx runs fn with queries bound to one transaction, which is committed
+// if fn succeeds and rolled back otherwise. Commands that write in several
+// steps use it so they either happen completely or not at all.
+func (s *State) withTx(ctx context.Context, fn func(q *database.Queries) error) error {
+	tx, err := s.Conn.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("could not begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := fn(s.Db.WithTx(tx)); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("could not commit transaction: %w", err)
+	}
+	return nil
 }
@@ -3,6 +3,8 @@ module github.com/fotis-sofoulis/blog-aggregator
 go 1.24.3
 
 require (
-	github.com/google/uuid v1.6.0 // indirect
-	github.com/lib/pq v1.10.9 // indirect
+	github.com/google/uuid v1.6.0
+	github.com/lib/pq v1.10.9
+	golang.org/x/net v0.50.0
+	golang.org/x/text v0.34.0
 )
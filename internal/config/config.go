@@ -10,6 +10,12 @@ import (
 type Config struct {
 	DbUrl           string `json:"db_url"`
 	CurrentUserName string `json:"current_user_name"`
+
+	// Feed fetching limits. Timeouts are durations such as "10s"; empty
+	// values fall back to the defaults in app.NewFetcher.
+	FetchConnectTimeout string `json:"fetch_connect_timeout,omitempty"`
+	FetchReadTimeout    string `json:"fetch_read_timeout,omitempty"`
+	FetchMaxBodyBytes   int64  `json:"fetch_max_body_bytes,omitempty"`
 }
 
 func (c *Config)SetUser(currUserName string) error {
@@ -7,6 +7,7 @@ package database
 
 import (
 	"context"
+	"database/sql"
 	"time"
 
 	"github.com/google/uuid"
@@ -22,9 +23,9 @@ WITH inserted_feed_follow AS (
         $4,
         $5
         )
-    RETURNING id, created_at, updated_at, user_id, feed_id
+    RETURNING id, created_at, updated_at, user_id, feed_id, category
 )
-SELECT iff.id, iff.created_at, iff.updated_at, iff.user_id, iff.feed_id, u.name as user_name, f.name as feed_name
+SELECT iff.id, iff.created_at, iff.updated_at, iff.user_id, iff.feed_id, iff.category, u.name as user_name, f.name as feed_name
 FROM inserted_feed_follow iff
 JOIN users u ON iff.user_id = u.id
 JOIN feeds f ON iff.feed_id = f.id
@@ -44,6 +45,7 @@ type CreateFeedFollowRow struct {
 	UpdatedAt time.Time
 	UserID    uuid.UUID
 	FeedID    uuid.UUID
+	Category  sql.NullString
 	UserName  string
 	FeedName  string
 }
@@ -63,22 +65,56 @@ func (q *Queries) CreateFeedFollow(ctx context.Context, arg CreateFeedFollowPara
 		&i.UpdatedAt,
 		&i.UserID,
 		&i.FeedID,
+		&i.Category,
 		&i.UserName,
 		&i.FeedName,
 	)
 	return i, err
 }
 
+const createFeedFollowIfMissing = `-- name: CreateFeedFollowIfMissing :execrows
+INSERT INTO feed_follows (id, created_at, updated_at, user_id, feed_id, category)
+VALUES ($1, $2, $3, $4, $5, $6)
+ON CONFLICT (user_id, feed_id) DO NOTHING
+`
+
+type CreateFeedFollowIfMissingParams struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	UserID    uuid.UUID
+	FeedID    uuid.UUID
+	Category  sql.NullString
+}
+
+// Affects no rows when the user already follows the feed.
+func (q *Queries) CreateFeedFollowIfMissing(ctx context.Context, arg CreateFeedFollowIfMissingParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, createFeedFollowIfMissing,
+		arg.ID,
+		arg.CreatedAt,
+		arg.UpdatedAt,
+		arg.UserID,
+		arg.FeedID,
+		arg.Category,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
 const deleteFeedFollowByUserAndUrl = `-- name: DeleteFeedFollowByUserAndUrl :one
 WITH deleted_follow AS (
     DELETE FROM feed_follows ff
     WHERE ff.user_id = $1
-    AND ff.feed_id = (
+    AND ff.feed_id IN (
         SELECT f.id FROM feeds f WHERE f.url = $2
+        UNION
+        SELECT fa.feed_id FROM feed_aliases fa WHERE fa.url = $2
     )
-    RETURNING id, created_at, updated_at, user_id, feed_id
+    RETURNING id, created_at, updated_at, user_id, feed_id, category
 )
-SELECT df.id, df.created_at, df.updated_at, df.user_id, df.feed_id, f.name as feed_name
+SELECT df.id, df.created_at, df.updated_at, df.user_id, df.feed_id, df.category, f.name as feed_name
 FROM deleted_follow df
 JOIN feeds f ON f.id = df.feed_id
 `
@@ -94,6 +130,7 @@ type DeleteFeedFollowByUserAndUrlRow struct {
 	UpdatedAt time.Time
 	UserID    uuid.UUID
 	FeedID    uuid.UUID
+	Category  sql.NullString
 	FeedName  string
 }
 
@@ -106,13 +143,14 @@ func (q *Queries) DeleteFeedFollowByUserAndUrl(ctx context.Context, arg DeleteFe
 		&i.UpdatedAt,
 		&i.UserID,
 		&i.FeedID,
+		&i.Category,
 		&i.FeedName,
 	)
 	return i, err
 }
 
 const getFeedFollowsForUser = `-- name: GetFeedFollowsForUser :many
-SELECT f.name AS feed_name, u.name AS user_name
+SELECT f.name AS feed_name, u.name AS user_name, ff.category
 FROM feed_follows ff
 JOIN users u ON ff.user_id = u.id
 JOIN feeds f ON ff.feed_id = f.id
@@ -122,6 +160,7 @@ WHERE ff.user_id = $1
 type GetFeedFollowsForUserRow struct {
 	FeedName string
 	UserName string
+	Category sql.NullString
 }
 
 func (q *Queries) GetFeedFollowsForUser(ctx context.Context, userID uuid.UUID) ([]GetFeedFollowsForUserRow, error) {
@@ -133,7 +172,7 @@ func (q *Queries) GetFeedFollowsForUser(ctx context.Context, userID uuid.UUID) (
 	var items []GetFeedFollowsForUserRow
 	for rows.Next() {
 		var i GetFeedFollowsForUserRow
-		if err := rows.Scan(&i.FeedName, &i.UserName); err != nil {
+		if err := rows.Scan(&i.FeedName, &i.UserName, &i.Category); err != nil {
 			return nil, err
 		}
 		items = append(items, i)
@@ -146,3 +185,40 @@ func (q *Queries) GetFeedFollowsForUser(ctx context.Context, userID uuid.UUID) (
 	}
 	return items, nil
 }
+
+const moveFeedFollows = `-- name: MoveFeedFollows :exec
+UPDATE feed_follows SET feed_id = $1, updated_at = NOW()
+WHERE feed_id = $2
+AND user_id NOT IN (SELECT user_id FROM feed_follows WHERE feed_id = $1)
+`
+
+type MoveFeedFollowsParams struct {
+	ToFeedID   uuid.UUID
+	FromFeedID uuid.UUID
+}
+
+// Users who already follow the target feed keep their existing follow.
+func (q *Queries) MoveFeedFollows(ctx context.Context, arg MoveFeedFollowsParams) error {
+	_, err := q.db.ExecContext(ctx, moveFeedFollows, arg.ToFeedID, arg.FromFeedID)
+	return err
+}
+
+const setFeedFollowCategory = `-- name: SetFeedFollowCategory :execrows
+UPDATE feed_follows SET category = $3, updated_at = NOW()
+WHERE user_id = $1 AND feed_id = $2 AND category IS DISTINCT FROM $3
+`
+
+type SetFeedFollowCategoryParams struct {
+	UserID   uuid.UUID
+	FeedID   uuid.UUID
+	Category sql.NullString
+}
+
+// Affects no rows when the follow already has this category.
+func (q *Queries) SetFeedFollowCategory(ctx context.Context, arg SetFeedFollowCategoryParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, setFeedFollowCategory, arg.UserID, arg.FeedID, arg.Category)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
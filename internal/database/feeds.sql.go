@@ -7,31 +7,38 @@ package database
 
 import (
 	"context"
+	"database/sql"
 	"time"
 
 	"github.com/google/uuid"
 )
 
 const addFeed = `-- name: AddFeed :one
-INSERT INTO feeds (id, name, user_id, url, created_at, updated_at)
+INSERT INTO feeds (id, name, user_id, url, created_at, updated_at, poll_interval, site_link, description)
 VALUES (
     $1,
     $2,
     $3,
     $4,
     $5,
-    $6
+    $6,
+    $7,
+    $8,
+    $9
 )
-RETURNING id, name, user_id, url, created_at, updated_at, last_fetched_at
+RETURNING id, name, user_id, url, created_at, updated_at, last_fetched_at, etag, last_modified, lease_expires_at, consecutive_failures, next_fetch_at, disabled_at, disabled_reason, min_interval, skip_hours, skip_days, poll_interval, parse_warning, site_link, description
 `
 
 type AddFeedParams struct {
-	ID        uuid.UUID
-	Name      string
-	UserID    uuid.UUID
-	Url       string
-	CreatedAt time.Time
-	UpdatedAt time.Time
+	ID           uuid.UUID
+	Name         string
+	UserID       uuid.UUID
+	Url          string
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+	PollInterval sql.NullInt32
+	SiteLink     sql.NullString
+	Description  sql.NullString
 }
 
 func (q *Queries) AddFeed(ctx context.Context, arg AddFeedParams) (Feed, error) {
@@ -42,6 +49,9 @@ func (q *Queries) AddFeed(ctx context.Context, arg AddFeedParams) (Feed, error)
 		arg.Url,
 		arg.CreatedAt,
 		arg.UpdatedAt,
+		arg.PollInterval,
+		arg.SiteLink,
+		arg.Description,
 	)
 	var i Feed
 	err := row.Scan(
@@ -52,14 +62,208 @@ func (q *Queries) AddFeed(ctx context.Context, arg AddFeedParams) (Feed, error)
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.LastFetchedAt,
+		&i.Etag,
+		&i.LastModified,
+		&i.LeaseExpiresAt,
+		&i.ConsecutiveFailures,
+		&i.NextFetchAt,
+		&i.DisabledAt,
+		&i.DisabledReason,
+		&i.MinInterval,
+		&i.SkipHours,
+		&i.SkipDays,
+		&i.PollInterval,
+		&i.ParseWarning,
+		&i.SiteLink,
+		&i.Description,
 	)
 	return i, err
 }
 
+const claimFeed = `-- name: ClaimFeed :one
+UPDATE feeds
+SET lease_expires_at = NOW() + make_interval(secs => $1::INTEGER)
+WHERE id = $2 AND (lease_expires_at IS NULL OR lease_expires_at < NOW())
+RETURNING id, name, user_id, url, created_at, updated_at, last_fetched_at, etag, last_modified, lease_expires_at, consecutive_failures, next_fetch_at, disabled_at, disabled_reason, min_interval, skip_hours, skip_days, poll_interval, parse_warning, site_link, description
+`
+
+type ClaimFeedParams struct {
+	LeaseSeconds int32
+	ID           uuid.UUID
+}
+
+// Leases one specific feed whatever its schedule, unless another worker
+// holds it.
+func (q *Queries) ClaimFeed(ctx context.Context, arg ClaimFeedParams) (Feed, error) {
+	row := q.db.QueryRowContext(ctx, claimFeed, arg.LeaseSeconds, arg.ID)
+	var i Feed
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.UserID,
+		&i.Url,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.LastFetchedAt,
+		&i.Etag,
+		&i.LastModified,
+		&i.LeaseExpiresAt,
+		&i.ConsecutiveFailures,
+		&i.NextFetchAt,
+		&i.DisabledAt,
+		&i.DisabledReason,
+		&i.MinInterval,
+		&i.SkipHours,
+		&i.SkipDays,
+		&i.PollInterval,
+		&i.ParseWarning,
+		&i.SiteLink,
+		&i.Description,
+	)
+	return i, err
+}
+
+const claimNextFeed = `-- name: ClaimNextFeed :one
+UPDATE feeds
+SET lease_expires_at = NOW() + make_interval(secs => $1::INTEGER)
+WHERE id = (
+    SELECT id FROM feeds
+    WHERE (lease_expires_at IS NULL OR lease_expires_at < NOW())
+    AND disabled_at IS NULL
+    AND (next_fetch_at IS NULL OR next_fetch_at <= NOW())
+    ORDER BY next_fetch_at ASC NULLS FIRST, last_fetched_at ASC NULLS FIRST
+    LIMIT 1
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING id, name, user_id, url, created_at, updated_at, last_fetched_at, etag, last_modified, lease_expires_at, consecutive_failures, next_fetch_at, disabled_at, disabled_reason, min_interval, skip_hours, skip_days, poll_interval, parse_warning, site_link, description
+`
+
+// Leasing the feed in the same statement that selects it keeps concurrent
+// aggregators, on this host or another, from claiming the same feed. A
+// worker that dies leaves the lease to expire and the feed becomes
+// eligible again.
+func (q *Queries) ClaimNextFeed(ctx context.Context, leaseSeconds int32) (Feed, error) {
+	row := q.db.QueryRowContext(ctx, claimNextFeed, leaseSeconds)
+	var i Feed
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.UserID,
+		&i.Url,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.LastFetchedAt,
+		&i.Etag,
+		&i.LastModified,
+		&i.LeaseExpiresAt,
+		&i.ConsecutiveFailures,
+		&i.NextFetchAt,
+		&i.DisabledAt,
+		&i.DisabledReason,
+		&i.MinInterval,
+		&i.SkipHours,
+		&i.SkipDays,
+		&i.PollInterval,
+		&i.ParseWarning,
+		&i.SiteLink,
+		&i.Description,
+	)
+	return i, err
+}
+
+const deleteFeed = `-- name: DeleteFeed :exec
+DELETE FROM feeds WHERE id = $1
+`
+
+func (q *Queries) DeleteFeed(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, deleteFeed, id)
+	return err
+}
+
+const disableFeed = `-- name: DisableFeed :exec
+UPDATE feeds SET updated_at = NOW(), disabled_at = NOW(), disabled_reason = $2 WHERE id = $1
+`
+
+type DisableFeedParams struct {
+	ID             uuid.UUID
+	DisabledReason sql.NullString
+}
+
+func (q *Queries) DisableFeed(ctx context.Context, arg DisableFeedParams) error {
+	_, err := q.db.ExecContext(ctx, disableFeed, arg.ID, arg.DisabledReason)
+	return err
+}
+
+const enableFeed = `-- name: EnableFeed :exec
+UPDATE feeds
+SET updated_at = NOW(), disabled_at = NULL, disabled_reason = NULL, consecutive_failures = 0, next_fetch_at = NULL
+WHERE id = $1
+`
+
+func (q *Queries) EnableFeed(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, enableFeed, id)
+	return err
+}
+
+const getBrokenFeeds = `-- name: GetBrokenFeeds :many
+SELECT id, name, user_id, url, created_at, updated_at, last_fetched_at, etag, last_modified, lease_expires_at, consecutive_failures, next_fetch_at, disabled_at, disabled_reason, min_interval, skip_hours, skip_days, poll_interval, parse_warning, site_link, description FROM feeds
+WHERE disabled_at IS NOT NULL OR consecutive_failures > 0
+ORDER BY disabled_at ASC NULLS LAST, consecutive_failures DESC
+`
+
+func (q *Queries) GetBrokenFeeds(ctx context.Context) ([]Feed, error) {
+	rows, err := q.db.QueryContext(ctx, getBrokenFeeds)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Feed
+	for rows.Next() {
+		var i Feed
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.UserID,
+			&i.Url,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.LastFetchedAt,
+			&i.Etag,
+			&i.LastModified,
+			&i.LeaseExpiresAt,
+			&i.ConsecutiveFailures,
+			&i.NextFetchAt,
+			&i.DisabledAt,
+			&i.DisabledReason,
+			&i.MinInterval,
+			&i.SkipHours,
+			&i.SkipDays,
+			&i.PollInterval,
+			&i.ParseWarning,
+			&i.SiteLink,
+			&i.Description,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getFeedByUrl = `-- name: GetFeedByUrl :one
-SELECT id, name, user_id, url, created_at, updated_at, last_fetched_at FROM feeds WHERE url = $1
+SELECT id, name, user_id, url, created_at, updated_at, last_fetched_at, etag, last_modified, lease_expires_at, consecutive_failures, next_fetch_at, disabled_at, disabled_reason, min_interval, skip_hours, skip_days, poll_interval, parse_warning, site_link, description FROM feeds
+WHERE url = $1 OR id IN (SELECT feed_id FROM feed_aliases WHERE feed_aliases.url = $1)
+ORDER BY url = $1 DESC
+LIMIT 1
 `
 
+// Also finds a feed by an address it was permanently redirected from.
 func (q *Queries) GetFeedByUrl(ctx context.Context, url string) (Feed, error) {
 	row := q.db.QueryRowContext(ctx, getFeedByUrl, url)
 	var i Feed
@@ -71,6 +275,20 @@ func (q *Queries) GetFeedByUrl(ctx context.Context, url string) (Feed, error) {
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.LastFetchedAt,
+		&i.Etag,
+		&i.LastModified,
+		&i.LeaseExpiresAt,
+		&i.ConsecutiveFailures,
+		&i.NextFetchAt,
+		&i.DisabledAt,
+		&i.DisabledReason,
+		&i.MinInterval,
+		&i.SkipHours,
+		&i.SkipDays,
+		&i.PollInterval,
+		&i.ParseWarning,
+		&i.SiteLink,
+		&i.Description,
 	)
 	return i, err
 }
@@ -108,30 +326,125 @@ func (q *Queries) GetFeeds(ctx context.Context) ([]GetFeedsRow, error) {
 	return items, nil
 }
 
-const getNextFeedToFetch = `-- name: GetNextFeedToFetch :one
-SELECT id, name, user_id, url, created_at, updated_at, last_fetched_at FROM feeds ORDER BY last_fetched_at ASC NULLS FIRST LIMIT 1
+const markFeedFetched = `-- name: MarkFeedFetched :exec
+UPDATE feeds SET updated_at = NOW(), last_fetched_at = NOW(), lease_expires_at = NULL WHERE id = $1
 `
 
-func (q *Queries) GetNextFeedToFetch(ctx context.Context) (Feed, error) {
-	row := q.db.QueryRowContext(ctx, getNextFeedToFetch)
-	var i Feed
-	err := row.Scan(
-		&i.ID,
-		&i.Name,
-		&i.UserID,
-		&i.Url,
-		&i.CreatedAt,
-		&i.UpdatedAt,
-		&i.LastFetchedAt,
+func (q *Queries) MarkFeedFetched(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, markFeedFetched, id)
+	return err
+}
+
+const recordFeedFailure = `-- name: RecordFeedFailure :exec
+UPDATE feeds SET consecutive_failures = consecutive_failures + 1, next_fetch_at = $2 WHERE id = $1
+`
+
+type RecordFeedFailureParams struct {
+	ID          uuid.UUID
+	NextFetchAt sql.NullTime
+}
+
+func (q *Queries) RecordFeedFailure(ctx context.Context, arg RecordFeedFailureParams) error {
+	_, err := q.db.ExecContext(ctx, recordFeedFailure, arg.ID, arg.NextFetchAt)
+	return err
+}
+
+const recordFeedSuccess = `-- name: RecordFeedSuccess :exec
+UPDATE feeds
+SET consecutive_failures = 0, next_fetch_at = $2, min_interval = $3, skip_hours = $4, skip_days = $5
+WHERE id = $1
+`
+
+type RecordFeedSuccessParams struct {
+	ID          uuid.UUID
+	NextFetchAt sql.NullTime
+	MinInterval sql.NullInt32
+	SkipHours   sql.NullString
+	SkipDays    sql.NullString
+}
+
+func (q *Queries) RecordFeedSuccess(ctx context.Context, arg RecordFeedSuccessParams) error {
+	_, err := q.db.ExecContext(ctx, recordFeedSuccess,
+		arg.ID,
+		arg.NextFetchAt,
+		arg.MinInterval,
+		arg.SkipHours,
+		arg.SkipDays,
 	)
-	return i, err
+	return err
 }
 
-const markFeedFetched = `-- name: MarkFeedFetched :exec
-UPDATE feeds SET updated_at = NOW(), last_fetched_at = NOW() WHERE id = $1
+const releaseFeedLease = `-- name: ReleaseFeedLease :exec
+UPDATE feeds SET lease_expires_at = NULL WHERE id = $1
 `
 
-func (q *Queries) MarkFeedFetched(ctx context.Context, id uuid.UUID) error {
-	_, err := q.db.ExecContext(ctx, markFeedFetched, id)
+func (q *Queries) ReleaseFeedLease(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, releaseFeedLease, id)
+	return err
+}
+
+const setFeedCacheValidators = `-- name: SetFeedCacheValidators :exec
+UPDATE feeds SET etag = $2, last_modified = $3 WHERE id = $1
+`
+
+type SetFeedCacheValidatorsParams struct {
+	ID           uuid.UUID
+	Etag         sql.NullString
+	LastModified sql.NullString
+}
+
+func (q *Queries) SetFeedCacheValidators(ctx context.Context, arg SetFeedCacheValidatorsParams) error {
+	_, err := q.db.ExecContext(ctx, setFeedCacheValidators, arg.ID, arg.Etag, arg.LastModified)
+	return err
+}
+
+const setFeedParseWarning = `-- name: SetFeedParseWarning :exec
+UPDATE feeds SET parse_warning = $2 WHERE id = $1
+`
+
+type SetFeedParseWarningParams struct {
+	ID           uuid.UUID
+	ParseWarning sql.NullString
+}
+
+func (q *Queries) SetFeedParseWarning(ctx context.Context, arg SetFeedParseWarningParams) error {
+	_, err := q.db.ExecContext(ctx, setFeedParseWarning, arg.ID, arg.ParseWarning)
+	return err
+}
+
+const setFeedPollInterval = `-- name: SetFeedPollInterval :exec
+UPDATE feeds
+SET updated_at = NOW(),
+    poll_interval = $2,
+    next_fetch_at = CASE
+        WHEN next_fetch_at IS NULL THEN NULL
+        ELSE LEAST(next_fetch_at, NOW() + make_interval(secs => $2))
+    END
+WHERE id = $1
+`
+
+type SetFeedPollIntervalParams struct {
+	ID           uuid.UUID
+	PollInterval sql.NullInt32
+}
+
+// Shortening the interval brings the next fetch forward rather than waiting
+// out the old schedule. A feed that was never fetched stays due now.
+func (q *Queries) SetFeedPollInterval(ctx context.Context, arg SetFeedPollIntervalParams) error {
+	_, err := q.db.ExecContext(ctx, setFeedPollInterval, arg.ID, arg.PollInterval)
+	return err
+}
+
+const setFeedUrl = `-- name: SetFeedUrl :exec
+UPDATE feeds SET updated_at = NOW(), url = $2 WHERE id = $1
+`
+
+type SetFeedUrlParams struct {
+	ID  uuid.UUID
+	Url string
+}
+
+func (q *Queries) SetFeedUrl(ctx context.Context, arg SetFeedUrlParams) error {
+	_, err := q.db.ExecContext(ctx, setFeedUrl, arg.ID, arg.Url)
 	return err
 }
@@ -12,13 +12,45 @@ import (
 )
 
 type Feed struct {
+	ID                  uuid.UUID
+	Name                string
+	UserID              uuid.UUID
+	Url                 string
+	CreatedAt           time.Time
+	UpdatedAt           time.Time
+	LastFetchedAt       sql.NullTime
+	Etag                sql.NullString
+	LastModified        sql.NullString
+	LeaseExpiresAt      sql.NullTime
+	ConsecutiveFailures int32
+	NextFetchAt         sql.NullTime
+	DisabledAt          sql.NullTime
+	DisabledReason      sql.NullString
+	MinInterval         sql.NullInt32
+	SkipHours           sql.NullString
+	SkipDays            sql.NullString
+	PollInterval        sql.NullInt32
+	ParseWarning        sql.NullString
+	SiteLink            sql.NullString
+	Description         sql.NullString
+}
+
+type FeedAlias struct {
+	Url       string
+	FeedID    uuid.UUID
+	CreatedAt time.Time
+}
+
+type FeedFetch struct {
 	ID            uuid.UUID
-	Name          string
-	UserID        uuid.UUID
-	Url           string
-	CreatedAt     time.Time
-	UpdatedAt     time.Time
-	LastFetchedAt sql.NullTime
+	FeedID        uuid.UUID
+	FetchedAt     time.Time
+	StatusCode    sql.NullInt32
+	DurationMs    int32
+	Bytes         int64
+	ItemsSeen     int32
+	ItemsInserted int32
+	Error         sql.NullString
 }
 
 type FeedFollow struct {
@@ -27,17 +59,39 @@ type FeedFollow struct {
 	UpdatedAt time.Time
 	UserID    uuid.UUID
 	FeedID    uuid.UUID
+	Category  sql.NullString
 }
 
 type Post struct {
-	ID          uuid.UUID
-	CreatedAt   time.Time
-	UpdatedAt   time.Time
-	Title       string
-	Url         string
-	Description sql.NullString
-	PublishedAt time.Time
-	FeedID      uuid.UUID
+	ID                   uuid.UUID
+	CreatedAt            time.Time
+	UpdatedAt            time.Time
+	Title                string
+	Url                  string
+	Description          sql.NullString
+	PublishedAt          time.Time
+	FeedID               uuid.UUID
+	PublishedAtEstimated bool
+	Guid                 string
+	ContentHash          string
+	Revisions            int32
+	Author               sql.NullString
+	Content              sql.NullString
+}
+
+type PostCategory struct {
+	PostID uuid.UUID
+	Name   string
+}
+
+type PostEnclosure struct {
+	ID           uuid.UUID
+	PostID       uuid.UUID
+	Url          string
+	MimeType     sql.NullString
+	Length       sql.NullInt64
+	DownloadedAt sql.NullTime
+	LocalPath    sql.NullString
 }
 
 type User struct {
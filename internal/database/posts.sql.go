@@ -13,85 +13,72 @@ import (
 	"github.com/google/uuid"
 )
 
-const createPost = `-- name: CreatePost :one
-INSERT INTO posts (id, created_at, updated_at, title, url, description, published_at, feed_id)
-VALUES (
-    $1,
-    $2,
-    $3,
-    $4,
-    $5,
-    $6,
-    $7,
-    $8
-)
-RETURNING id, created_at, updated_at, title, url, description, published_at, feed_id
+const getFeedPostingInterval = `-- name: GetFeedPostingInterval :one
+SELECT COALESCE(
+    EXTRACT(EPOCH FROM MAX(published_at) - MIN(published_at)) / NULLIF(COUNT(*) - 1, 0),
+    0
+)::FLOAT8 AS average_seconds
+FROM (
+    SELECT published_at FROM posts
+    WHERE feed_id = $1 AND NOT published_at_estimated
+    ORDER BY published_at DESC
+    LIMIT 20
+) recent
 `
 
-type CreatePostParams struct {
-	ID          uuid.UUID
-	CreatedAt   time.Time
-	UpdatedAt   time.Time
-	Title       string
-	Url         string
-	Description sql.NullString
-	PublishedAt time.Time
-	FeedID      uuid.UUID
-}
-
-func (q *Queries) CreatePost(ctx context.Context, arg CreatePostParams) (Post, error) {
-	row := q.db.QueryRowContext(ctx, createPost,
-		arg.ID,
-		arg.CreatedAt,
-		arg.UpdatedAt,
-		arg.Title,
-		arg.Url,
-		arg.Description,
-		arg.PublishedAt,
-		arg.FeedID,
-	)
-	var i Post
-	err := row.Scan(
-		&i.ID,
-		&i.CreatedAt,
-		&i.UpdatedAt,
-		&i.Title,
-		&i.Url,
-		&i.Description,
-		&i.PublishedAt,
-		&i.FeedID,
-	)
-	return i, err
+// Average seconds between the feed's recent posts, or 0 with fewer than two.
+func (q *Queries) GetFeedPostingInterval(ctx context.Context, feedID uuid.UUID) (float64, error) {
+	row := q.db.QueryRowContext(ctx, getFeedPostingInterval, feedID)
+	var average_seconds float64
+	err := row.Scan(&average_seconds)
+	return average_seconds, err
 }
 
 const getPostsForUser = `-- name: GetPostsForUser :many
-SELECT p.id, p.created_at, p.updated_at, p.title, p.url, p.description, p.published_at, p.feed_id, f.name AS feed_name FROM posts p
+SELECT p.id, p.created_at, p.updated_at, p.title, p.url, p.description, p.published_at, p.feed_id, p.published_at_estimated, p.guid, p.content_hash, p.revisions, p.author, p.content, f.name AS feed_name FROM posts p
 JOIN feed_follows ff ON p.feed_id = ff.feed_id
 JOIN feeds f ON p.feed_id = f.id
 WHERE ff.user_id = $1
+AND (NOT $2::BOOLEAN OR p.revisions > 0)
+AND ($3::TEXT = '' OR EXISTS (
+    SELECT 1 FROM post_categories pc WHERE pc.post_id = p.id AND pc.name = $3
+))
 ORDER BY p.published_at DESC
-LIMIT $2
+LIMIT $4
 `
 
 type GetPostsForUserParams struct {
-	UserID uuid.UUID
-	Limit  int32
+	UserID      uuid.UUID
+	UpdatedOnly bool
+	Category    string
+	Limit       int32
 }
 
 type GetPostsForUserRow struct {
-	ID          uuid.UUID
-	CreatedAt   time.Time
-	UpdatedAt   time.Time
-	Title       string
-	Url         string
-	Description sql.NullString
-	PublishedAt time.Time
-	FeedID      uuid.UUID
-	FeedName    string
+	ID                   uuid.UUID
+	CreatedAt            time.Time
+	UpdatedAt            time.Time
+	Title                string
+	Url                  string
+	Description          sql.NullString
+	PublishedAt          time.Time
+	FeedID               uuid.UUID
+	PublishedAtEstimated bool
+	Guid                 string
+	ContentHash          string
+	Revisions            int32
+	Author               sql.NullString
+	Content              sql.NullString
+	FeedName             string
 }
 
 func (q *Queries) GetPostsForUser(ctx context.Context, arg GetPostsForUserParams) ([]GetPostsForUserRow, error) {
-	rows, err := q.db.QueryContext(ctx, getPostsForUser, arg.UserID, arg.Limit)
+	rows, err := q.db.QueryContext(ctx, getPostsForUser,
+		arg.UserID,
+		arg.UpdatedOnly,
+		arg.Category,
+		arg.Limit,
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -108,6 +95,12 @@ func (q *Queries) GetPostsForUser(ctx context.Context, arg GetPostsForUserParams
 			&i.Description,
 			&i.PublishedAt,
 			&i.FeedID,
+			&i.PublishedAtEstimated,
+			&i.Guid,
+			&i.ContentHash,
+			&i.Revisions,
+			&i.Author,
+			&i.Content,
 			&i.FeedName,
 		); err != nil {
 			return nil, err
@@ -122,3 +115,138 @@ func (q *Queries) GetPostsForUser(ctx context.Context, arg GetPostsForUserParams
 	}
 	return items, nil
 }
+
+const moveFeedPosts = `-- name: MoveFeedPosts :exec
+UPDATE posts SET feed_id = $1
+WHERE feed_id = $2
+AND guid NOT IN (SELECT guid FROM posts WHERE feed_id = $1)
+`
+
+type MoveFeedPostsParams struct {
+	ToFeedID   uuid.UUID
+	FromFeedID uuid.UUID
+}
+
+// Posts the target feed already has by guid stay behind.
+func (q *Queries) MoveFeedPosts(ctx context.Context, arg MoveFeedPostsParams) error {
+	_, err := q.db.ExecContext(ctx, moveFeedPosts, arg.ToFeedID, arg.FromFeedID)
+	return err
+}
+
+const replaceLegacyPost = `-- name: ReplaceLegacyPost :execrows
+WITH legacy AS (
+    DELETE FROM posts
+    WHERE feed_id = $1 AND url = $2 AND guid = url AND id <> $3
+    RETURNING created_at, published_at, published_at_estimated
+)
+UPDATE posts
+SET created_at = legacy.created_at,
+    published_at = CASE WHEN posts.published_at_estimated THEN legacy.published_at ELSE posts.published_at END,
+    published_at_estimated = posts.published_at_estimated AND legacy.published_at_estimated
+FROM legacy
+WHERE posts.id = $3
+`
+
+type ReplaceLegacyPostParams struct {
+	FeedID uuid.UUID
+	Url    string
+	ID     uuid.UUID
+}
+
+// Posts stored before guids were tracked have their url as guid, so an item
+// with a real guid is inserted again next to them. This drops the old copy
+// of a newly inserted post, keeping when it was first seen.
+func (q *Queries) ReplaceLegacyPost(ctx context.Context, arg ReplaceLegacyPostParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, replaceLegacyPost, arg.FeedID, arg.Url, arg.ID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const upsertPost = `-- name: UpsertPost :one
+INSERT INTO posts (id, created_at, updated_at, title, url, description, published_at, feed_id, published_at_estimated, guid, content_hash, author, content)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4,
+    $5,
+    $6,
+    $7,
+    $8,
+    $9,
+    $10,
+    $11,
+    $12,
+    $13
+)
+ON CONFLICT (feed_id, guid) DO UPDATE SET
+    updated_at = EXCLUDED.updated_at,
+    title = EXCLUDED.title,
+    url = EXCLUDED.url,
+    description = EXCLUDED.description,
+    published_at = CASE WHEN EXCLUDED.published_at_estimated THEN posts.published_at ELSE EXCLUDED.published_at END,
+    published_at_estimated = posts.published_at_estimated AND EXCLUDED.published_at_estimated,
+    author = EXCLUDED.author,
+    content = EXCLUDED.content,
+    content_hash = EXCLUDED.content_hash,
+    revisions = posts.revisions + CASE WHEN posts.content_hash = '' THEN 0 ELSE 1 END
+WHERE posts.content_hash <> EXCLUDED.content_hash
+RETURNING id, created_at, updated_at, title, url, description, published_at, feed_id, published_at_estimated, guid, content_hash, revisions, author, content
+`
+
+type UpsertPostParams struct {
+	ID                   uuid.UUID
+	CreatedAt            time.Time
+	UpdatedAt            time.Time
+	Title                string
+	Url                  string
+	Description          sql.NullString
+	PublishedAt          time.Time
+	FeedID               uuid.UUID
+	PublishedAtEstimated bool
+	Guid                 string
+	ContentHash          string
+	Author               sql.NullString
+	Content              sql.NullString
+}
+
+// Updates a stored post only when its content hash changed, and returns no
+// rows when it did not. Posts stored before hashing have an empty hash and
+// take the first one without counting a revision.
+func (q *Queries) UpsertPost(ctx context.Context, arg UpsertPostParams) (Post, error) {
+	row := q.db.QueryRowContext(ctx, upsertPost,
+		arg.ID,
+		arg.CreatedAt,
+		arg.UpdatedAt,
+		arg.Title,
+		arg.Url,
+		arg.Description,
+		arg.PublishedAt,
+		arg.FeedID,
+		arg.PublishedAtEstimated,
+		arg.Guid,
+		arg.ContentHash,
+		arg.Author,
+		arg.Content,
+	)
+	var i Post
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Title,
+		&i.Url,
+		&i.Description,
+		&i.PublishedAt,
+		&i.FeedID,
+		&i.PublishedAtEstimated,
+		&i.Guid,
+		&i.ContentHash,
+		&i.Revisions,
+		&i.Author,
+		&i.Content,
+	)
+	return i, err
+}
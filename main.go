@@ -1,9 +1,12 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"log"
 	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/fotis-sofoulis/blog-aggregator/app"
 	"github.com/fotis-sofoulis/blog-aggregator/internal/config"
@@ -24,14 +27,21 @@ func main() {
 	}
 
 	dbQueries := database.New(db)
+
+	fetcher, err := app.NewFetcher(&conf)
+	if err != nil {
+		log.Fatal(err)
+	}
 	
 	s := &app.State {
-		Cfg : &conf,
-		Db  : dbQueries,
+		Cfg     : &conf,
+		Conn    : db,
+		Db      : dbQueries,
+		Fetcher : fetcher,
 	}
 
 	cmds := app.Commands{
-		RegisteredCommands: make(map[string]func(*app.State, app.Command) error),
+		RegisteredCommands: make(map[string]func(context.Context, *app.State, app.Command) error),
 	}
 	cmds.Register("login", app.HandlerLogin)
 	cmds.Register("register", app.HandlerRegister)
@@ -40,10 +50,15 @@ func main() {
 	cmds.Register("agg", app.HandlerAggregate)
 	cmds.Register("addfeed", app.MiddlewareLoggedIn(app.HandlerAddFeed))
 	cmds.Register("feeds", app.HandlerGetFeeds)
+	cmds.Register("feed-status", app.HandlerFeedStatus)
+	cmds.Register("feed-enable", app.HandlerFeedEnable)
+	cmds.Register("feed-set", app.HandlerFeedSet)
 	cmds.Register("follow", app.MiddlewareLoggedIn(app.HandlerFollowFeed))
 	cmds.Register("following", app.MiddlewareLoggedIn(app.HandlerFollowing))
 	cmds.Register("unfollow", app.MiddlewareLoggedIn(app.HandlerUnfollow))
 	cmds.Register("browse", app.MiddlewareLoggedIn(app.HandlerBrowse))
+	cmds.Register("download", app.MiddlewareLoggedIn(app.HandlerDownload))
+	cmds.Register("import-opml", app.MiddlewareLoggedIn(app.HandlerImportOPML))
 
 	if len(os.Args) < 2 {
 		log.Fatal("Usage: cli <command> [args...]")
@@ -52,7 +67,17 @@ func main() {
 	name := os.Args[1]
 	args := os.Args[2:]
 
-	if err := cmds.Run(s, app.Command{Name: name, Args: args}); err != nil {
+	// The first SIGINT or SIGTERM cancels ctx so commands can wind down;
+	// once stop has run a second signal kills the process as usual.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+
+	err = cmds.Run(ctx, s, app.Command{Name: name, Args: args})
+	stop()
+	if err != nil {
 		log.Fatal(err)
 	}
 
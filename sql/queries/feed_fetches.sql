@@ -0,0 +1,32 @@
+-- name: CreateFeedFetch :exec
+INSERT INTO feed_fetches (id, feed_id, fetched_at, status_code, duration_ms, bytes, items_seen, items_inserted, error)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4,
+    $5,
+    $6,
+    $7,
+    $8,
+    $9
+);
+
+-- name: GetFeedFetches :many
+SELECT * FROM feed_fetches
+WHERE feed_id = $1
+ORDER BY fetched_at DESC
+LIMIT $2;
+
+-- name: PruneFeedFetches :exec
+-- Keeps the feed's latest fetches and deletes the older ones.
+DELETE FROM feed_fetches
+WHERE feed_id = $1 AND id NOT IN (
+    SELECT id FROM feed_fetches
+    WHERE feed_id = $1
+    ORDER BY fetched_at DESC
+    LIMIT $2
+);
+
+-- name: MoveFeedFetches :exec
+UPDATE feed_fetches SET feed_id = sqlc.arg(to_feed_id) WHERE feed_id = sqlc.arg(from_feed_id);
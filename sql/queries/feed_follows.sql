@@ -15,8 +15,14 @@ FROM inserted_feed_follow iff
 JOIN users u ON iff.user_id = u.id
 JOIN feeds f ON iff.feed_id = f.id;
 
+-- name: CreateFeedFollowIfMissing :execrows
+-- Affects no rows when the user already follows the feed.
+INSERT INTO feed_follows (id, created_at, updated_at, user_id, feed_id, category)
+VALUES ($1, $2, $3, $4, $5, $6)
+ON CONFLICT (user_id, feed_id) DO NOTHING;
+
 -- name: GetFeedFollowsForUser :many
-SELECT f.name AS feed_name, u.name AS user_name
+SELECT f.name AS feed_name, u.name AS user_name, ff.category
 FROM feed_follows ff
 JOIN users u ON ff.user_id = u.id
 JOIN feeds f ON ff.feed_id = f.id
@@ -26,11 +32,24 @@ WHERE ff.user_id = $1;
 WITH deleted_follow AS (
     DELETE FROM feed_follows ff
     WHERE ff.user_id = $1
-    AND ff.feed_id = (
+    AND ff.feed_id IN (
         SELECT f.id FROM feeds f WHERE f.url = $2
+        UNION
+        SELECT fa.feed_id FROM feed_aliases fa WHERE fa.url = $2
     )
     RETURNING *
 )
 SELECT df.*, f.name as feed_name
 FROM deleted_follow df
 JOIN feeds f ON f.id = df.feed_id;
+
+-- name: MoveFeedFollows :exec
+-- Users who already follow the target feed keep their existing follow.
+UPDATE feed_follows SET feed_id = sqlc.arg(to_feed_id), updated_at = NOW()
+WHERE feed_id = sqlc.arg(from_feed_id)
+AND user_id NOT IN (SELECT user_id FROM feed_follows WHERE feed_id = sqlc.arg(to_feed_id));
+
+-- name: SetFeedFollowCategory :execrows
+-- Affects no rows when the follow already has this category.
+UPDATE feed_follows SET category = $3, updated_at = NOW()
+WHERE user_id = $1 AND feed_id = $2 AND category IS DISTINCT FROM $3;
@@ -1,12 +1,15 @@
 -- name: AddFeed :one
-INSERT INTO feeds (id, name, user_id, url, created_at, updated_at)
+INSERT INTO feeds (id, name, user_id, url, created_at, updated_at, poll_interval, site_link, description)
 VALUES (
     $1,
     $2,
     $3,
     $4,
     $5,
-    $6
+    $6,
+    $7,
+    $8,
+    $9
 )
 RETURNING *;
 
@@ -14,10 +17,85 @@ RETURNING *;
 SELECT f.name AS feed_name, f.url, u.name as user_name FROM feeds f JOIN users u ON f.user_id = u.id;
 
 -- name: GetFeedByUrl :one
-SELECT * FROM feeds WHERE url = $1;
+-- Also finds a feed by an address it was permanently redirected from.
+SELECT * FROM feeds
+WHERE url = $1 OR id IN (SELECT feed_id FROM feed_aliases WHERE feed_aliases.url = $1)
+ORDER BY url = $1 DESC
+LIMIT 1;
 
 -- name: MarkFeedFetched :exec
-UPDATE feeds SET updated_at = NOW(), last_fetched_at = NOW() WHERE id = $1;
+UPDATE feeds SET updated_at = NOW(), last_fetched_at = NOW(), lease_expires_at = NULL WHERE id = $1;
 
--- name: GetNextFeedToFetch :one
-SELECT * FROM feeds ORDER BY last_fetched_at ASC NULLS FIRST LIMIT 1;
+-- name: ClaimNextFeed :one
+-- Leasing the feed in the same statement that selects it keeps concurrent
+-- aggregators, on this host or another, from claiming the same feed. A
+-- worker that dies leaves the lease to expire and the feed becomes
+-- eligible again.
+UPDATE feeds
+SET lease_expires_at = NOW() + make_interval(secs => sqlc.arg(lease_seconds)::INTEGER)
+WHERE id = (
+    SELECT id FROM feeds
+    WHERE (lease_expires_at IS NULL OR lease_expires_at < NOW())
+    AND disabled_at IS NULL
+    AND (next_fetch_at IS NULL OR next_fetch_at <= NOW())
+    ORDER BY next_fetch_at ASC NULLS FIRST, last_fetched_at ASC NULLS FIRST
+    LIMIT 1
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING *;
+
+-- name: ClaimFeed :one
+-- Leases one specific feed whatever its schedule, unless another worker
+-- holds it.
+UPDATE feeds
+SET lease_expires_at = NOW() + make_interval(secs => sqlc.arg(lease_seconds)::INTEGER)
+WHERE id = sqlc.arg(id) AND (lease_expires_at IS NULL OR lease_expires_at < NOW())
+RETURNING *;
+
+-- name: ReleaseFeedLease :exec
+UPDATE feeds SET lease_expires_at = NULL WHERE id = $1;
+
+-- name: SetFeedCacheValidators :exec
+UPDATE feeds SET etag = $2, last_modified = $3 WHERE id = $1;
+
+-- name: RecordFeedSuccess :exec
+UPDATE feeds
+SET consecutive_failures = 0, next_fetch_at = $2, min_interval = $3, skip_hours = $4, skip_days = $5
+WHERE id = $1;
+
+-- name: RecordFeedFailure :exec
+UPDATE feeds SET consecutive_failures = consecutive_failures + 1, next_fetch_at = $2 WHERE id = $1;
+
+-- name: DisableFeed :exec
+UPDATE feeds SET updated_at = NOW(), disabled_at = NOW(), disabled_reason = $2 WHERE id = $1;
+
+-- name: EnableFeed :exec
+UPDATE feeds
+SET updated_at = NOW(), disabled_at = NULL, disabled_reason = NULL, consecutive_failures = 0, next_fetch_at = NULL
+WHERE id = $1;
+
+-- name: GetBrokenFeeds :many
+SELECT * FROM feeds
+WHERE disabled_at IS NOT NULL OR consecutive_failures > 0
+ORDER BY disabled_at ASC NULLS LAST, consecutive_failures DESC;
+
+-- name: SetFeedPollInterval :exec
+-- Shortening the interval brings the next fetch forward rather than waiting
+-- out the old schedule. A feed that was never fetched stays due now.
+UPDATE feeds
+SET updated_at = NOW(),
+    poll_interval = $2,
+    next_fetch_at = CASE
+        WHEN next_fetch_at IS NULL THEN NULL
+        ELSE LEAST(next_fetch_at, NOW() + make_interval(secs => $2))
+    END
+WHERE id = $1;
+
+-- name: SetFeedParseWarning :exec
+UPDATE feeds SET parse_warning = $2 WHERE id = $1;
+
+-- name: SetFeedUrl :exec
+UPDATE feeds SET updated_at = NOW(), url = $2 WHERE id = $1;
+
+-- name: DeleteFeed :exec
+DELETE FROM feeds WHERE id = $1;
@@ -0,0 +1,24 @@
+-- name: UpsertPostEnclosure :exec
+INSERT INTO post_enclosures (id, post_id, url, mime_type, length)
+VALUES ($1, $2, $3, $4, $5)
+ON CONFLICT (post_id, url) DO UPDATE SET
+    mime_type = EXCLUDED.mime_type,
+    length = EXCLUDED.length;
+
+-- name: GetPostEnclosures :many
+SELECT * FROM post_enclosures WHERE post_id = $1 ORDER BY url;
+
+-- name: GetPendingEnclosures :many
+-- Enclosures of the user's feeds that have not been downloaded yet, oldest
+-- post first. A null feed_id matches every feed.
+SELECT e.*, p.title AS post_title, f.name AS feed_name FROM post_enclosures e
+JOIN posts p ON e.post_id = p.id
+JOIN feeds f ON p.feed_id = f.id
+JOIN feed_follows ff ON f.id = ff.feed_id
+WHERE ff.user_id = sqlc.arg(user_id)
+AND e.downloaded_at IS NULL
+AND (sqlc.narg(feed_id)::UUID IS NULL OR f.id = sqlc.narg(feed_id))
+ORDER BY p.published_at ASC;
+
+-- name: MarkEnclosureDownloaded :exec
+UPDATE post_enclosures SET downloaded_at = NOW(), local_path = $2 WHERE id = $1;
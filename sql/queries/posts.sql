@@ -1,5 +1,8 @@
--- name: CreatePost :one
-INSERT INTO posts (id, created_at, updated_at, title, url, description, published_at, feed_id)
+-- name: UpsertPost :one
+-- Updates a stored post only when its content hash changed, and returns no
+-- rows when it did not. Posts stored before hashing have an empty hash and
+-- take the first one without counting a revision.
+INSERT INTO posts (id, created_at, updated_at, title, url, description, published_at, feed_id, published_at_estimated, guid, content_hash, author, content)
 VALUES (
     $1,
     $2,
@@ -8,14 +11,70 @@ VALUES (
     $5,
     $6,
     $7,
-    $8
+    $8,
+    $9,
+    $10,
+    $11,
+    $12,
+    $13
 )
+ON CONFLICT (feed_id, guid) DO UPDATE SET
+    updated_at = EXCLUDED.updated_at,
+    title = EXCLUDED.title,
+    url = EXCLUDED.url,
+    description = EXCLUDED.description,
+    published_at = CASE WHEN EXCLUDED.published_at_estimated THEN posts.published_at ELSE EXCLUDED.published_at END,
+    published_at_estimated = posts.published_at_estimated AND EXCLUDED.published_at_estimated,
+    author = EXCLUDED.author,
+    content = EXCLUDED.content,
+    content_hash = EXCLUDED.content_hash,
+    revisions = posts.revisions + CASE WHEN posts.content_hash = '' THEN 0 ELSE 1 END
+WHERE posts.content_hash <> EXCLUDED.content_hash
 RETURNING *;
 
 -- name: GetPostsForUser :many
 SELECT p.*, f.name AS feed_name FROM posts p
 JOIN feed_follows ff ON p.feed_id = ff.feed_id
 JOIN feeds f ON p.feed_id = f.id
-WHERE ff.user_id = $1
+WHERE ff.user_id = sqlc.arg(user_id)
+AND (NOT sqlc.arg(updated_only)::BOOLEAN OR p.revisions > 0)
+AND (sqlc.arg(category)::TEXT = '' OR EXISTS (
+    SELECT 1 FROM post_categories pc WHERE pc.post_id = p.id AND pc.name = sqlc.arg(category)
+))
 ORDER BY p.published_at DESC
-LIMIT $2;
+LIMIT sqlc.arg('limit');
+
+-- name: GetFeedPostingInterval :one
+-- Average seconds between the feed's recent posts, or 0 with fewer than two.
+SELECT COALESCE(
+    EXTRACT(EPOCH FROM MAX(published_at) - MIN(published_at)) / NULLIF(COUNT(*) - 1, 0),
+    0
+)::FLOAT8 AS average_seconds
+FROM (
+    SELECT published_at FROM posts
+    WHERE feed_id = $1 AND NOT published_at_estimated
+    ORDER BY published_at DESC
+    LIMIT 20
+) recent;
+
+-- name: MoveFeedPosts :exec
+-- Posts the target feed already has by guid stay behind.
+UPDATE posts SET feed_id = sqlc.arg(to_feed_id)
+WHERE feed_id = sqlc.arg(from_feed_id)
+AND guid NOT IN (SELECT guid FROM posts WHERE feed_id = sqlc.arg(to_feed_id));
+
+-- name: ReplaceLegacyPost :execrows
+-- Posts stored before guids were tracked have their url as guid, so an item
+-- with a real guid is inserted again next to them. This drops the old copy
+-- of a newly inserted post, keeping when it was first seen.
+WITH legacy AS (
+    DELETE FROM posts
+    WHERE feed_id = sqlc.arg(feed_id) AND url = sqlc.arg(url) AND guid = url AND id <> sqlc.arg(id)
+    RETURNING created_at, published_at, published_at_estimated
+)
+UPDATE posts
+SET created_at = legacy.created_at,
+    published_at = CASE WHEN posts.published_at_estimated THEN legacy.published_at ELSE posts.published_at END,
+    published_at_estimated = posts.published_at_estimated AND legacy.published_at_estimated
+FROM legacy
+WHERE posts.id = sqlc.arg(id);
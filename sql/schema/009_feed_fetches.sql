@@ -0,0 +1,18 @@
+-- +goose Up
+CREATE TABLE feed_fetches (
+    id UUID PRIMARY KEY,
+    feed_id UUID NOT NULL,
+    fetched_at TIMESTAMP NOT NULL,
+    status_code INTEGER,
+    duration_ms INTEGER NOT NULL,
+    bytes BIGINT NOT NULL,
+    items_seen INTEGER NOT NULL,
+    items_inserted INTEGER NOT NULL,
+    error TEXT,
+    CONSTRAINT fk_feed_fetches_feed FOREIGN KEY(feed_id) REFERENCES feeds(id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_feed_fetches_feed_fetched_at ON feed_fetches (feed_id, fetched_at DESC);
+
+-- +goose Down
+DROP TABLE feed_fetches;
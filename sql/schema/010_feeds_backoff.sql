@@ -0,0 +1,13 @@
+-- +goose Up
+ALTER TABLE feeds ADD COLUMN consecutive_failures INTEGER NOT NULL DEFAULT 0;
+-- Computed by gator rather than the database, so it keeps its time zone to
+-- compare correctly with NOW() whatever zone either side runs in.
+ALTER TABLE feeds ADD COLUMN next_fetch_at TIMESTAMPTZ;
+ALTER TABLE feeds ADD COLUMN disabled_at TIMESTAMP;
+ALTER TABLE feeds ADD COLUMN disabled_reason TEXT;
+
+-- +goose Down
+ALTER TABLE feeds DROP COLUMN disabled_reason;
+ALTER TABLE feeds DROP COLUMN disabled_at;
+ALTER TABLE feeds DROP COLUMN next_fetch_at;
+ALTER TABLE feeds DROP COLUMN consecutive_failures;
@@ -0,0 +1,12 @@
+-- +goose Up
+-- Seconds between fetches the feed itself asks for through <ttl> or sy:updatePeriod.
+ALTER TABLE feeds ADD COLUMN min_interval INTEGER;
+-- The hours (0-23, GMT) and weekdays the feed asks not to be fetched in,
+-- through <skipHours> and <skipDays>, as comma separated lists.
+ALTER TABLE feeds ADD COLUMN skip_hours TEXT;
+ALTER TABLE feeds ADD COLUMN skip_days TEXT;
+
+-- +goose Down
+ALTER TABLE feeds DROP COLUMN skip_days;
+ALTER TABLE feeds DROP COLUMN skip_hours;
+ALTER TABLE feeds DROP COLUMN min_interval;
@@ -0,0 +1,6 @@
+-- +goose Up
+-- Seconds between fetches set by a user, overriding the feed's own hints.
+ALTER TABLE feeds ADD COLUMN poll_interval INTEGER;
+
+-- +goose Down
+ALTER TABLE feeds DROP COLUMN poll_interval;
@@ -0,0 +1,21 @@
+-- +goose Up
+ALTER TABLE posts ADD COLUMN author TEXT;
+ALTER TABLE posts ADD COLUMN content TEXT;
+
+CREATE TABLE post_categories (
+    post_id UUID NOT NULL,
+    name TEXT NOT NULL,
+    PRIMARY KEY (post_id, name),
+    CONSTRAINT fk_post_categories_post FOREIGN KEY(post_id) REFERENCES posts(id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_post_categories_name ON post_categories (name);
+
+-- The content hash now covers these fields. Clearing it lets the next fetch
+-- fill them in without counting a revision for every stored post.
+UPDATE posts SET content_hash = '';
+
+-- +goose Down
+DROP TABLE post_categories;
+ALTER TABLE posts DROP COLUMN content;
+ALTER TABLE posts DROP COLUMN author;
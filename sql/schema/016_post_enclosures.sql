@@ -0,0 +1,18 @@
+-- +goose Up
+CREATE TABLE post_enclosures (
+    id UUID PRIMARY KEY,
+    post_id UUID NOT NULL,
+    url TEXT NOT NULL,
+    mime_type TEXT,
+    length BIGINT,
+    downloaded_at TIMESTAMP,
+    local_path TEXT,
+    CONSTRAINT fk_post_enclosures_post FOREIGN KEY(post_id) REFERENCES posts(id) ON DELETE CASCADE,
+    CONSTRAINT uniq_post_enclosure UNIQUE (post_id, url)
+);
+
+-- Enclosures now count towards the content hash; see 015.
+UPDATE posts SET content_hash = '';
+
+-- +goose Down
+DROP TABLE post_enclosures;